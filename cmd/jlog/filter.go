@@ -0,0 +1,108 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/oresoftware/json-logging/jlog/bunion"
+)
+
+func runFilter(args []string) error {
+	var fs = flag.NewFlagSet("filter", flag.ExitOnError)
+	var cf = addCommonFlags(fs)
+	var opts = addPrettyFlags(fs)
+
+	var expr, output, level, app, host, since, until string
+	var pid int
+	var isKeepOther bool
+	fs.StringVar(&expr, "e", "", "the expression, when given all the arguments are files")
+	fs.StringVar(&output, "o", "auto", "output: pretty, json (the original lines) or auto (pretty on a terminal)")
+	fs.StringVar(&level, "level", "", "minimum level, same as level>=LEVEL")
+	fs.StringVar(&app, "app", "", "same as app=APP")
+	fs.StringVar(&host, "host", "", "same as host=HOST")
+	fs.IntVar(&pid, "pid", 0, "same as pid=PID")
+	fs.StringVar(&since, "since", "", "same as time>=SINCE, eg 15m or 2024-01-02T10:00:00Z")
+	fs.StringVar(&until, "until", "", "same as time<UNTIL")
+	fs.BoolVar(&isKeepOther, "keep-other", false, "pass through lines that are not records")
+	fs.Parse(args)
+
+	var rest = fs.Args()
+
+	// without -e the first argument is the expression, the rest are files
+	if expr == "" && len(rest) > 0 {
+		expr, rest = rest[0], rest[1:]
+	}
+
+	var terms []string
+	if strings.TrimSpace(expr) != "" {
+		terms = append(terms, "("+expr+")")
+	}
+
+	var addTerm = func(field, op, val string) {
+		if val != "" {
+			terms = append(terms, field+op+strconv.Quote(val))
+		}
+	}
+	addTerm("level", ">=", level)
+	addTerm("app", "=", app)
+	addTerm("host", "=", host)
+	addTerm("time", ">=", since)
+	addTerm("time", "<", until)
+	if pid > 0 {
+		addTerm("pid", "=", strconv.Itoa(pid))
+	}
+
+	f, err := bunion.ParseFilter(strings.Join(terms, " and "))
+	if err != nil {
+		return err
+	}
+
+	isJSON, err := parseOutput(output)
+	if err != nil {
+		return err
+	}
+
+	var isColor = cf.apply(os.Stdout)
+
+	inputs, closeAll, err := openInputs(rest)
+	if err != nil {
+		return err
+	}
+	defer closeAll()
+
+	var p = newPrinter(os.Stdout, isJSON, isColor, *opts)
+	defer p.out.Flush()
+
+	for _, in := range inputs {
+		err := decodeStream(in.r, p.out, func(rec *bunion.Record, line []byte) {
+			switch {
+			case rec == nil:
+				if isKeepOther {
+					p.other(line)
+				}
+			case f.Match(rec):
+				p.record(rec)
+			}
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func parseOutput(output string) (bool, error) {
+	switch output {
+	case "json":
+		return true, nil
+	case "pretty":
+		return false, nil
+	case "auto":
+		return !isTerminal(os.Stdout), nil
+	}
+	return false, fmt.Errorf("unknown output %q, use pretty, json or auto", output)
+}
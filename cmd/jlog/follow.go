@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/logrusorgru/aurora/v4"
+	au "github.com/oresoftware/json-logging/jlog/au"
+	"github.com/oresoftware/json-logging/jlog/bunion"
+	"github.com/oresoftware/json-logging/jlog/tail"
+)
+
+func runFollow(args []string) error {
+	var fs = flag.NewFlagSet("follow", flag.ExitOnError)
+	var cf = addCommonFlags(fs)
+	var opts = addPrettyFlags(fs)
+
+	var expr, output string
+	var isFromStart, isNoSource bool
+	var window, poll time.Duration
+	fs.StringVar(&expr, "e", "", "only show records matching this filter expression (see jlog filter)")
+	fs.StringVar(&output, "o", "auto", "output: pretty, json (the original lines) or auto (pretty on a terminal)")
+	fs.BoolVar(&isFromStart, "from-start", false, "read the files from the beginning instead of only new lines")
+	fs.BoolVar(&isNoSource, "no-source", false, "do not prefix lines with the file they came from")
+	fs.DurationVar(&window, "window", 500*time.Millisecond, "how long records are held back to be merged by timestamp")
+	fs.DurationVar(&poll, "poll", 250*time.Millisecond, "how often the files are checked for new lines")
+	fs.Parse(args)
+
+	if fs.NArg() < 1 {
+		return errors.New("follow needs at least one file")
+	}
+
+	f, err := bunion.ParseFilter(expr)
+	if err != nil {
+		return err
+	}
+
+	isJSON, err := parseOutput(output)
+	if err != nil {
+		return err
+	}
+
+	var isColor = cf.apply(os.Stdout)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	entries, err := tail.Follow(ctx, tail.FollowParams{
+		Paths:        fs.Args(),
+		FromStart:    isFromStart,
+		PollInterval: poll,
+		Window:       window,
+	})
+	if err != nil {
+		return err
+	}
+
+	var p = newPrinter(os.Stdout, isJSON, isColor, *opts)
+	defer p.out.Flush()
+
+	// the source prefix is only for people, json output stays chainable
+	var prefixes = sourcePrefixes(fs.Args())
+	var isPrefixed = !isJSON && !isNoSource && len(prefixes) > 1
+
+	for e := range entries {
+		if e.Record != nil && !f.Match(e.Record) {
+			continue
+		}
+		if isPrefixed {
+			p.out.WriteString(prefixes[e.Source])
+		}
+		if e.Record == nil {
+			p.other(e.Line)
+		} else {
+			p.record(e.Record)
+		}
+		if len(entries) == 0 {
+			if err := p.out.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// sourcePrefixes colors each file name differently, padded to the same width
+func sourcePrefixes(paths []string) []string {
+	var palette = []func(arg interface{}) aurora.Value{
+		au.Col.Cyan, au.Col.Magenta, au.Col.Yellow, au.Col.Blue, au.Col.Green, au.Col.Red,
+		au.Col.BrightCyan, au.Col.BrightMagenta, au.Col.BrightYellow, au.Col.BrightBlue,
+	}
+
+	var width = 0
+	for _, p := range paths {
+		if len(p) > width {
+			width = len(p)
+		}
+	}
+
+	var prefixes = make([]string, len(paths))
+	for i, p := range paths {
+		prefixes[i] = palette[i%len(palette)](fmt.Sprintf("%-*s", width, p)).String() + " | "
+	}
+	return prefixes
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/logrusorgru/aurora/v4"
+	au "github.com/oresoftware/json-logging/jlog/au"
+)
+
+const usage = `jlog reads @bunion:v1 log lines and renders them the way a terminal logger would.
+
+usage:
+  jlog [pretty] [flags] [files...]
+  jlog filter [flags] [expression] [files...]
+  jlog filter [flags] -e expression [files...]
+  jlog follow [flags] files...
+  jlog stats [flags] [files...]
+
+with no files, or with "-", stdin is read. Lines that are not @bunion records are passed through untouched.
+
+commands:
+  pretty    colorized output (the default)
+  filter    only the records matching an expression, eg:
+              jlog filter 'level>=warn app=api (requestId=abc or log_num>1000)' app.log
+            terms are "field op value" or a bare field (present), ops are = != > >= < <= ~ !~,
+            combined with and/or/not and parens. fields are level, app, host, pid, time, msg,
+            anything else is a meta field (dotted names reach into nested objects).
+  follow    tail several files at once, like tail -F, merging the records by timestamp
+  stats     count records by level, app, host, pid, time bucket or meta fields, eg:
+              jlog stats -e 'level=error' -by time,app -bucket 1m app.log
+              jlog stats -by log_id -top 10 -o json app.log
+`
+
+type command struct {
+	name string
+	run  func(args []string) error
+}
+
+var commands = []command{
+	{"pretty", runPretty},
+	{"filter", runFilter},
+	{"follow", runFollow},
+	{"stats", runStats},
+}
+
+func main() {
+	var args = os.Args[1:]
+	var run = runPretty
+
+	if len(args) > 0 {
+		switch args[0] {
+		case "help", "-h", "--help":
+			fmt.Fprint(os.Stderr, usage)
+			return
+		}
+		for _, c := range commands {
+			if args[0] == c.name {
+				run = c.run
+				args = args[1:]
+				break
+			}
+		}
+	}
+
+	if err := run(args); err != nil {
+		fmt.Fprintln(os.Stderr, "jlog:", err)
+		os.Exit(1)
+	}
+}
+
+// commonFlags are shared by every command
+type commonFlags struct {
+	color string
+}
+
+func addCommonFlags(fs *flag.FlagSet) *commonFlags {
+	var c = &commonFlags{}
+	fs.StringVar(&c.color, "color", "auto", "colorize output: auto, always or never")
+	fs.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		fmt.Fprintf(os.Stderr, "\nflags for %s:\n", fs.Name())
+		fs.PrintDefaults()
+	}
+	return c
+}
+
+// apply sets up colors for out, it returns false if output should not be colorized
+func (c *commonFlags) apply(out *os.File) bool {
+	var isColor bool
+	switch c.color {
+	case "always":
+		isColor = true
+	case "never":
+		isColor = false
+	default:
+		isColor = isTerminal(out) && os.Getenv("vibe_with_color") != "no"
+	}
+	au.Col = aurora.New(aurora.WithColors(isColor))
+	return isColor
+}
+
+// openInputs opens the named files, "-" or no names at all means stdin
+func openInputs(names []string) ([]namedReader, func(), error) {
+	if len(names) < 1 {
+		names = []string{"-"}
+	}
+
+	var readers []namedReader
+	var closers []io.Closer
+
+	closeAll := func() {
+		for _, c := range closers {
+			c.Close()
+		}
+	}
+
+	for _, n := range names {
+		if n == "-" {
+			readers = append(readers, namedReader{"stdin", os.Stdin})
+			continue
+		}
+		f, err := os.Open(n)
+		if err != nil {
+			closeAll()
+			return nil, nil, err
+		}
+		closers = append(closers, f)
+		readers = append(readers, namedReader{n, f})
+	}
+
+	return readers, closeAll, nil
+}
+
+type namedReader struct {
+	name string
+	r    io.Reader
+}
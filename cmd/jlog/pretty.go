@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"io"
+	"os"
+	"regexp"
+
+	"github.com/oresoftware/json-logging/jlog/bunion"
+	ll "github.com/oresoftware/json-logging/jlog/level"
+	"github.com/oresoftware/json-logging/jlog/lib"
+	"golang.org/x/term"
+)
+
+func isTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
+
+type prettyOpts struct {
+	isShowMeta bool
+	isLocalTZ  bool
+}
+
+func addPrettyFlags(fs *flag.FlagSet) *prettyOpts {
+	var opts = &prettyOpts{}
+	fs.BoolVar(&opts.isShowMeta, "meta", true, "render meta fields other than log_id/log_num")
+	fs.BoolVar(&opts.isLocalTZ, "local", false, "show times in the local time zone instead of UTC")
+	return opts
+}
+
+func runPretty(args []string) error {
+	var fs = flag.NewFlagSet("pretty", flag.ExitOnError)
+	var cf = addCommonFlags(fs)
+	var opts = addPrettyFlags(fs)
+	fs.Parse(args)
+
+	var isColor = cf.apply(os.Stdout)
+
+	inputs, closeAll, err := openInputs(fs.Args())
+	if err != nil {
+		return err
+	}
+	defer closeAll()
+
+	var p = newPrinter(os.Stdout, false, isColor, *opts)
+	defer p.out.Flush()
+
+	for _, in := range inputs {
+		err := decodeStream(in.r, p.out, func(rec *bunion.Record, line []byte) {
+			if rec == nil {
+				p.other(line)
+			} else {
+				p.record(rec)
+			}
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// printer writes records either pretty or as the original JSON line
+type printer struct {
+	out     *bufio.Writer
+	isJSON  bool
+	isColor bool
+	opts    prettyOpts
+}
+
+func newPrinter(w io.Writer, isJSON, isColor bool, opts prettyOpts) *printer {
+	return &printer{out: bufio.NewWriter(w), isJSON: isJSON, isColor: isColor, opts: opts}
+}
+
+// the helper package colorizes values regardless of au.Col
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+func (p *printer) record(rec *bunion.Record) {
+	if p.isJSON {
+		// Spaces/Tabs output can precede a record
+		p.out.Write(bytes.TrimLeft(rec.Raw, " \t"))
+		p.out.WriteByte('\n')
+		return
+	}
+	var s = renderRecord(rec, p.opts)
+	if !p.isColor {
+		s = ansiEscape.ReplaceAllString(s, "")
+	}
+	p.out.WriteString(s)
+}
+
+func (p *printer) other(line []byte) {
+	p.out.Write(line)
+	p.out.WriteByte('\n')
+}
+
+// decodeStream calls fn for every line, rec is nil for lines that are not records
+func decodeStream(r io.Reader, out *bufio.Writer, fn func(rec *bunion.Record, line []byte)) error {
+	var d = bunion.NewDecoder(r)
+
+	for {
+		rec, line, err := d.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		fn(rec, line)
+
+		// keep up with streams such as kubectl logs -f
+		if d.Buffered() == 0 {
+			if err := out.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func renderRecord(rec *bunion.Record, opts prettyOpts) string {
+	var date = rec.RawDate
+	if !rec.Date.IsZero() {
+		if opts.isLocalTZ {
+			date = rec.Date.Local().Format("15:04:05.000000")
+		} else {
+			date = rec.Date.Format("15:04:05.000000")
+		}
+	}
+
+	var meta = lib.MF{}
+	for k, v := range rec.Meta {
+		meta[k] = v
+	}
+
+	// unknown levels render as <undefined>, like the logger does
+	level, ok := ll.Parse(rec.Level)
+	if !ok {
+		level = -1
+	}
+
+	return lib.PrettyString(date, level, rec.AppName, lib.NewMetaFields(&meta), rec.Args, opts.isShowMeta)
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oresoftware/json-logging/jlog/bunion"
+)
+
+const fixture = `plain text before
+["@bunion:v1","api","INFO",42,"host-1","2024-01-02 03:04:05.000006",{"log_id":"abcdef0123456789","log_num":7,"user":"ada"},["hello",1]]
+{"not":"a record"}
+["@bunion:v1","worker","WARN",43,"host-2","2024-01-02 03:04:06.000000",{"log_num":8},["careful"]]
+`
+
+// writeFixture writes s to a file in a temp dir and returns its path
+func writeFixture(t *testing.T, s string) string {
+	t.Helper()
+	var path = filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(path, []byte(s), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// captureStdout runs a command and returns what it wrote to stdout
+func captureStdout(t *testing.T, run func(args []string) error, args ...string) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout = os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	var out = make(chan []byte)
+	go func() {
+		b, _ := io.ReadAll(r)
+		out <- b
+	}()
+
+	err = run(args)
+	w.Close()
+	var b = <-out
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestPrettyRendersRecordsAndPassesOtherLinesThrough(t *testing.T) {
+	var path = writeFixture(t, fixture)
+	var lines = strings.Split(strings.TrimRight(captureStdout(t, runPretty, "-color", "never", path), "\n"), "\n")
+
+	if len(lines) != 4 || lines[0] != "plain text before" || lines[2] != `{"not":"a record"}` {
+		t.Fatalf("expected the other lines untouched: %q", lines)
+	}
+
+	for _, want := range []string{"03:04:05.000006", "INFO", "app:api", "(log-id:ef0123456789)", "(log-num:7)", "user:ada", "hello"} {
+		if !strings.Contains(lines[1], want) {
+			t.Fatalf("expected %q in %q", want, lines[1])
+		}
+	}
+	if !strings.Contains(lines[3], "WARN") || !strings.Contains(lines[3], "(log-num:8)") || !strings.Contains(lines[3], "careful") {
+		t.Fatalf("unexpected second record: %q", lines[3])
+	}
+	if strings.Contains(strings.Join(lines, "\n"), "\x1b[") {
+		t.Fatalf("expected no colors with -color never: %q", lines)
+	}
+}
+
+func TestPrettyFlags(t *testing.T) {
+	var path = writeFixture(t, fixture)
+
+	for _, c := range []struct {
+		name    string
+		args    []string
+		want    []string
+		notWant []string
+	}{
+		{"color always", []string{"-color", "always"}, []string{"\x1b["}, nil},
+		{"no meta", []string{"-color", "never", "-meta=false"}, []string{"(log-num:7)", "hello"}, []string{"user:ada"}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			var out = captureStdout(t, runPretty, append(c.args, path)...)
+			for _, s := range c.want {
+				if !strings.Contains(out, s) {
+					t.Fatalf("expected %q in %q", s, out)
+				}
+			}
+			for _, s := range c.notWant {
+				if strings.Contains(out, s) {
+					t.Fatalf("expected no %q in %q", s, out)
+				}
+			}
+		})
+	}
+}
+
+func TestFilterJSONOutputWritesTheOriginalLines(t *testing.T) {
+	var path = writeFixture(t, fixture)
+	var lines = strings.Split(fixture, "\n")
+
+	var out = captureStdout(t, runFilter, "-o", "json", "level>=warn", path)
+	if out != lines[3]+"\n" {
+		t.Fatalf("expected the WARN line as it was: %q", out)
+	}
+
+	out = captureStdout(t, runFilter, "-o", "json", "-keep-other", "app=api", path)
+	if out != lines[0]+"\n"+lines[1]+"\n"+lines[2]+"\n" {
+		t.Fatalf("expected the api record and the other lines: %q", out)
+	}
+
+	out = captureStdout(t, runFilter, "-o", "pretty", "-color", "never", "app=worker", path)
+	if strings.HasPrefix(out, "[") || !strings.Contains(out, "careful") {
+		t.Fatalf("expected the worker record rendered: %q", out)
+	}
+}
+
+func TestRenderRecord(t *testing.T) {
+	var local = time.Local
+	time.Local = time.FixedZone("test", 2*60*60)
+	defer func() { time.Local = local }()
+
+	parse := func(line string) *bunion.Record {
+		rec, err := bunion.Parse([]byte(line))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rec
+	}
+
+	for _, c := range []struct {
+		name string
+		line string
+		opts prettyOpts
+		want string
+	}{
+		{"utc", strings.Split(fixture, "\n")[1], prettyOpts{isShowMeta: true}, "03:04:05.000006"},
+		{"local", strings.Split(fixture, "\n")[1], prettyOpts{isLocalTZ: true}, "05:04:05.000006"},
+		{"unknown level", `["@bunion:v1","api","LOUD",1,"h","2024-01-02 03:04:05.000000",{},["x"]]`, prettyOpts{}, "<undefined>"},
+		{"raw date", `["@bunion:v1","api","INFO",1,"h","yesterday",{},["x"]]`, prettyOpts{}, "yesterday"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			if s := ansiEscape.ReplaceAllString(renderRecord(parse(c.line), c.opts), ""); !strings.Contains(s, c.want) {
+				t.Fatalf("expected %q in %q", c.want, s)
+			}
+		})
+	}
+}
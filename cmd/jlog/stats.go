@@ -0,0 +1,273 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/oresoftware/json-logging/jlog/bunion"
+)
+
+const noValue = "<none>"
+
+// histogram counts records by one or more fields, eg level,app
+type histogram struct {
+	By     []string
+	counts map[string]*histRow
+}
+
+type histRow struct {
+	Keys  []string
+	Count int
+}
+
+func newHistogram(by []string) *histogram {
+	return &histogram{By: by, counts: map[string]*histRow{}}
+}
+
+func (h *histogram) add(rec *bunion.Record, bucket time.Duration) {
+	var keys = make([]string, len(h.By))
+	for i, field := range h.By {
+		keys[i] = statKey(rec, field, bucket)
+	}
+	var id = strings.Join(keys, "\x00")
+	row, ok := h.counts[id]
+	if !ok {
+		row = &histRow{Keys: keys}
+		h.counts[id] = row
+	}
+	row.Count++
+}
+
+func (h *histogram) hasTime() bool {
+	for _, f := range h.By {
+		if f == "time" || f == "date" {
+			return true
+		}
+	}
+	return false
+}
+
+// rows are sorted by count, or chronologically if the histogram is over time
+func (h *histogram) rows(top int) []*histRow {
+	var rows = make([]*histRow, 0, len(h.counts))
+	for _, r := range h.counts {
+		rows = append(rows, r)
+	}
+
+	var byKey = func(i, j int) bool {
+		for k := range rows[i].Keys {
+			if rows[i].Keys[k] != rows[j].Keys[k] {
+				return rows[i].Keys[k] < rows[j].Keys[k]
+			}
+		}
+		return false
+	}
+
+	if h.hasTime() {
+		sort.Slice(rows, byKey)
+	} else {
+		sort.Slice(rows, func(i, j int) bool {
+			if rows[i].Count != rows[j].Count {
+				return rows[i].Count > rows[j].Count
+			}
+			return byKey(i, j)
+		})
+	}
+
+	if top > 0 && len(rows) > top {
+		rows = rows[:top]
+	}
+	return rows
+}
+
+func statKey(rec *bunion.Record, field string, bucket time.Duration) string {
+	v, ok := rec.Field(field)
+	if !ok || v == nil {
+		return noValue
+	}
+	if t, ok := v.(time.Time); ok {
+		if t.IsZero() {
+			return noValue
+		}
+		return t.Truncate(bucket).Format("2006-01-02 15:04:05")
+	}
+	return bunion.FieldString(v)
+}
+
+// stringsFlag collects a repeated flag
+type stringsFlag []string
+
+func (s *stringsFlag) String() string {
+	return strings.Join(*s, " ")
+}
+
+func (s *stringsFlag) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
+
+func runStats(args []string) error {
+	var fs = flag.NewFlagSet("stats", flag.ExitOnError)
+	addCommonFlags(fs)
+
+	var by stringsFlag
+	var expr, output string
+	var bucket time.Duration
+	var top int
+	fs.Var(&by, "by", "fields to count by, comma separated fields are counted together, eg -by level,app -by time,level "+
+		"(repeatable, defaults to level, app, host and pid)")
+	fs.StringVar(&expr, "e", "", "only count records matching this filter expression (see jlog filter)")
+	fs.StringVar(&output, "o", "table", "output: table or json")
+	fs.DurationVar(&bucket, "bucket", time.Minute, "the size of the time buckets")
+	fs.IntVar(&top, "top", 0, "only show the N biggest rows of each histogram")
+	fs.Parse(args)
+
+	if output != "table" && output != "json" {
+		return fmt.Errorf("unknown output %q, use table or json", output)
+	}
+	if bucket <= 0 {
+		return fmt.Errorf("bucket must be positive, got %v", bucket)
+	}
+
+	f, err := bunion.ParseFilter(expr)
+	if err != nil {
+		return err
+	}
+
+	if len(by) < 1 {
+		by = stringsFlag{"level", "app", "host", "pid"}
+	}
+
+	var hists []*histogram
+	for _, b := range by {
+		var fields []string
+		for _, field := range strings.Split(b, ",") {
+			if field = strings.TrimSpace(field); field != "" {
+				fields = append(fields, field)
+			}
+		}
+		if len(fields) > 0 {
+			hists = append(hists, newHistogram(fields))
+		}
+	}
+
+	inputs, closeAll, err := openInputs(fs.Args())
+	if err != nil {
+		return err
+	}
+	defer closeAll()
+
+	var total, matched, skipped int
+
+	for _, in := range inputs {
+		var d = bunion.NewDecoder(in.r)
+		for {
+			rec, _, err := d.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			if rec == nil {
+				skipped++
+				continue
+			}
+			total++
+			if !f.Match(rec) {
+				continue
+			}
+			matched++
+			for _, h := range hists {
+				h.add(rec, bucket)
+			}
+		}
+	}
+
+	if output == "json" {
+		return writeStatsJSON(os.Stdout, hists, top, total, matched, skipped)
+	}
+	return writeStatsTable(os.Stdout, hists, top, total, matched, skipped)
+}
+
+func writeStatsJSON(w io.Writer, hists []*histogram, top, total, matched, skipped int) error {
+	type jsonRow struct {
+		Keys  map[string]interface{} `json:"keys"`
+		Count int                    `json:"count"`
+	}
+	type jsonHist struct {
+		By   []string  `json:"by"`
+		Rows []jsonRow `json:"rows"`
+	}
+
+	var out = struct {
+		Records    int        `json:"records"`
+		Matched    int        `json:"matched"`
+		Skipped    int        `json:"skipped"`
+		Histograms []jsonHist `json:"histograms"`
+	}{Records: total, Matched: matched, Skipped: skipped, Histograms: []jsonHist{}}
+
+	for _, h := range hists {
+		var jh = jsonHist{By: h.By, Rows: []jsonRow{}}
+		for _, r := range h.rows(top) {
+			var keys = map[string]interface{}{}
+			for i, k := range r.Keys {
+				if k == noValue {
+					keys[h.By[i]] = nil
+				} else {
+					keys[h.By[i]] = k
+				}
+			}
+			jh.Rows = append(jh.Rows, jsonRow{Keys: keys, Count: r.Count})
+		}
+		out.Histograms = append(out.Histograms, jh)
+	}
+
+	var enc = json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(out)
+}
+
+func writeStatsTable(w io.Writer, hists []*histogram, top, total, matched, skipped int) error {
+	const barWidth = 40
+
+	var tw = tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "records: %d, matched: %d, other lines: %d\n", total, matched, skipped)
+
+	for _, h := range hists {
+		var rows = h.rows(top)
+
+		var max = 0
+		for _, r := range rows {
+			if r.Count > max {
+				max = r.Count
+			}
+		}
+
+		fmt.Fprintln(tw)
+		fmt.Fprintf(tw, "%s\tcount\t%%\t\n", strings.Join(h.By, "\t"))
+
+		for _, r := range rows {
+			var pct = 0.0
+			if matched > 0 {
+				pct = 100 * float64(r.Count) / float64(matched)
+			}
+			var bar = ""
+			if max > 0 {
+				bar = strings.Repeat("#", (r.Count*barWidth+max-1)/max)
+			}
+			fmt.Fprintf(tw, "%s\t%d\t%.1f\t%s\n", strings.Join(r.Keys, "\t"), r.Count, pct, bar)
+		}
+	}
+
+	return tw.Flush()
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const statsFixture = `["@bunion:v1","api","INFO",1,"h1","2024-01-02 10:00:05.000000",{"user":"ada","http":{"status":200}},["a"]]
+["@bunion:v1","api","ERROR",1,"h1","2024-01-02 10:00:40.000000",{"user":"bob","http":{"status":500}},["b"]]
+not a record
+["@bunion:v1","worker","INFO",2,"h2","2024-01-02 10:01:10.000000",{"user":"ada"},["c"]]
+["@bunion:v1","api","INFO",1,"h1","2024-01-02 10:02:00.000000",{},["d"]]
+`
+
+type statsOutput struct {
+	Records    int `json:"records"`
+	Matched    int `json:"matched"`
+	Skipped    int `json:"skipped"`
+	Histograms []struct {
+		By   []string `json:"by"`
+		Rows []struct {
+			Keys  map[string]interface{} `json:"keys"`
+			Count int                    `json:"count"`
+		} `json:"rows"`
+	} `json:"histograms"`
+}
+
+// rows renders the rows of a histogram as "key=value,...:count", in their order
+func (s statsOutput) rows(i int) []string {
+	var results []string
+	for _, r := range s.Histograms[i].Rows {
+		var keys []string
+		for _, field := range s.Histograms[i].By {
+			keys = append(keys, fmt.Sprintf("%s=%v", field, r.Keys[field]))
+		}
+		results = append(results, fmt.Sprintf("%s:%d", strings.Join(keys, ","), r.Count))
+	}
+	return results
+}
+
+func TestStatsJSON(t *testing.T) {
+	var path = writeFixture(t, statsFixture)
+
+	for _, c := range []struct {
+		name    string
+		args    []string
+		matched int
+		want    [][]string
+	}{
+		{
+			"defaults", nil, 4, [][]string{
+				{"level=INFO:3", "level=ERROR:1"},
+				{"app=api:3", "app=worker:1"},
+				{"host=h1:3", "host=h2:1"},
+				{"pid=1:3", "pid=2:1"},
+			},
+		},
+		{
+			"time buckets are chronological", []string{"-by", "time"}, 4, [][]string{
+				{"time=2024-01-02 10:00:00:2", "time=2024-01-02 10:01:00:1", "time=2024-01-02 10:02:00:1"},
+			},
+		},
+		{
+			"bigger buckets", []string{"-by", "time", "-bucket", "2m"}, 4, [][]string{
+				{"time=2024-01-02 10:00:00:3", "time=2024-01-02 10:02:00:1"},
+			},
+		},
+		{
+			"fields counted together", []string{"-by", "time,level"}, 4, [][]string{
+				{"time=2024-01-02 10:00:00,level=ERROR:1", "time=2024-01-02 10:00:00,level=INFO:1",
+					"time=2024-01-02 10:01:00,level=INFO:1", "time=2024-01-02 10:02:00,level=INFO:1"},
+			},
+		},
+		{
+			"meta fields, missing ones are null", []string{"-by", "user", "-by", "http.status"}, 4, [][]string{
+				{"user=ada:2", "user=<nil>:1", "user=bob:1"},
+				{"http.status=<nil>:2", "http.status=200:1", "http.status=500:1"},
+			},
+		},
+		{
+			"filter and top", []string{"-e", "level=info", "-by", "app", "-top", "1"}, 3, [][]string{
+				{"app=api:2"},
+			},
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			var out statsOutput
+			var args = append(append([]string{"-o", "json"}, c.args...), path)
+			if err := json.Unmarshal([]byte(captureStdout(t, runStats, args...)), &out); err != nil {
+				t.Fatal(err)
+			}
+
+			if out.Records != 4 || out.Matched != c.matched || out.Skipped != 1 {
+				t.Fatalf("unexpected totals: %d %d %d", out.Records, out.Matched, out.Skipped)
+			}
+			if len(out.Histograms) != len(c.want) {
+				t.Fatalf("expected %d histograms, got %d", len(c.want), len(out.Histograms))
+			}
+			for i, want := range c.want {
+				if got := out.rows(i); fmt.Sprint(got) != fmt.Sprint(want) {
+					t.Fatalf("histogram %d: expected %v, got %v", i, want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestStatsTable(t *testing.T) {
+	var path = writeFixture(t, statsFixture)
+	var lines = strings.Split(strings.TrimRight(captureStdout(t, runStats, "-by", "level", "-by", "app,host", path), "\n"), "\n")
+
+	var want = []string{
+		"records: 4, matched: 4, other lines: 1",
+		"",
+		"level  count  %",
+		"INFO   3      75.0  ########################################",
+		"ERROR  1      25.0  ##############",
+		"",
+		"app     host  count  %",
+		"api     h1    3      75.0  ########################################",
+		"worker  h2    1      25.0  ##############",
+	}
+	for i := range lines {
+		lines[i] = strings.TrimRight(lines[i], " ")
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected table:\n%s", strings.Join(lines, "\n"))
+	}
+}
+
+func TestStatsRejectsBadFlags(t *testing.T) {
+	var path = writeFixture(t, statsFixture)
+	var errs []string
+	for _, args := range [][]string{
+		{"-o", "xml", path},
+		{"-bucket", "-1m", path},
+		{"-e", "level=", path},
+	} {
+		if err := runStats(args); err != nil {
+			errs = append(errs, args[0])
+		}
+	}
+	if fmt.Sprint(errs) != "[-o -bucket -e]" {
+		t.Fatalf("expected every bad flag to be rejected: %v", errs)
+	}
+}
@@ -1,19 +1,19 @@
 module github.com/oresoftware/json-logging
 
-go 1.18
+go 1.21
 
 require (
 	github.com/google/uuid v1.6.0
 	github.com/logrusorgru/aurora/v4 v4.0.0
+	github.com/mailru/easyjson v0.7.7
 	golang.org/x/crypto v0.19.0
+	golang.org/x/term v0.17.0
 )
 
 require (
 	github.com/josharian/intern v1.0.0 // indirect
 	github.com/json-iterator/go v1.1.12 // indirect
-	github.com/mailru/easyjson v0.7.7 // indirect
 	github.com/modern-go/concurrent v0.0.0-20180228061459-e0a39a4cb421 // indirect
 	github.com/modern-go/reflect2 v1.0.2 // indirect
 	golang.org/x/sys v0.17.0 // indirect
-	golang.org/x/term v0.17.0 // indirect
 )
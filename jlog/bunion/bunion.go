@@ -0,0 +1,220 @@
+package bunion
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// the @bunion array format written by lib and mult:
+// ["@bunion:v1", app, level, pid, host, date, {meta}, [args]]
+
+const MarkerPrefix = "@bunion:v"
+
+const DateFormat = "2006-01-02 15:04:05.000000"
+
+var ErrNotRecord = errors.New("json-logging: not a @bunion record")
+
+type Record struct {
+	Version  int
+	AppName  string
+	Level    string
+	PID      int
+	HostName string
+	Date     time.Time
+	RawDate  string
+	Meta     map[string]interface{}
+	Args     []interface{}
+	Raw      []byte // the original line, without the trailing newline
+}
+
+// MarshalJSON writes the record back in the array format.
+func (r *Record) MarshalJSON() ([]byte, error) {
+	var date = r.RawDate
+	if date == "" && !r.Date.IsZero() {
+		date = r.Date.UTC().Format(DateFormat)
+	}
+	var meta = r.Meta
+	if meta == nil {
+		meta = map[string]interface{}{}
+	}
+	var args = r.Args
+	if args == nil {
+		args = []interface{}{}
+	}
+	version := r.Version
+	if version < 1 {
+		version = 1
+	}
+	return json.Marshal([8]interface{}{
+		fmt.Sprintf("%s%d", MarkerPrefix, version), r.AppName, r.Level, r.PID, r.HostName, date, meta, args,
+	})
+}
+
+// LogId is meta["log_id"] as a string, or "".
+func (r *Record) LogId() string {
+	v, _ := r.Meta["log_id"].(string)
+	return v
+}
+
+// LogNum is meta["log_num"], or -1.
+func (r *Record) LogNum() int64 {
+	if v, ok := r.Meta["log_num"].(float64); ok {
+		return int64(v)
+	}
+	return -1
+}
+
+// findRecord returns the index where a record starts in the line, or -1.
+// Output from Spaces/Tabs has no newline, so a record can be preceded by whitespace.
+func findRecord(line []byte) int {
+	i := bytes.Index(line, []byte(`["`+MarkerPrefix))
+	if i < 0 {
+		return -1
+	}
+	if len(bytes.TrimSpace(line[:i])) > 0 {
+		return -1
+	}
+	return i
+}
+
+// ParseVersion returns N for a "@bunion:vN" marker.
+func ParseVersion(marker string) (int, bool) {
+	if !strings.HasPrefix(marker, MarkerPrefix) {
+		return 0, false
+	}
+	n, err := strconv.Atoi(strings.TrimPrefix(marker, MarkerPrefix))
+	if err != nil || n < 1 {
+		return 0, false
+	}
+	return n, true
+}
+
+// Parse decodes a single line, it returns ErrNotRecord if the line is not a @bunion record.
+func Parse(line []byte) (*Record, error) {
+	line = bytes.TrimRight(line, "\r\n")
+	i := findRecord(line)
+	if i < 0 {
+		return nil, ErrNotRecord
+	}
+
+	var fields []json.RawMessage
+	if err := json.Unmarshal(line[i:], &fields); err != nil {
+		return nil, ErrNotRecord
+	}
+
+	if len(fields) < 1 {
+		return nil, ErrNotRecord
+	}
+
+	var marker string
+	if err := json.Unmarshal(fields[0], &marker); err != nil {
+		return nil, ErrNotRecord
+	}
+
+	version, ok := ParseVersion(marker)
+	if !ok {
+		return nil, ErrNotRecord
+	}
+
+	var r = &Record{Version: version, Raw: append([]byte(nil), line...)}
+
+	// later versions are expected to append fields, so decode the v1 positions if they are there
+	if len(fields) < 8 {
+		if version == 1 {
+			return nil, fmt.Errorf("json-logging: @bunion:v1 record has %d fields, expected 8", len(fields))
+		}
+		return r, nil
+	}
+
+	var pid float64
+	var errs = []error{
+		json.Unmarshal(fields[1], &r.AppName),
+		json.Unmarshal(fields[2], &r.Level),
+		json.Unmarshal(fields[3], &pid),
+		json.Unmarshal(fields[4], &r.HostName),
+		json.Unmarshal(fields[5], &r.RawDate),
+		json.Unmarshal(fields[6], &r.Meta),
+		json.Unmarshal(fields[7], &r.Args),
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, fmt.Errorf("json-logging: malformed @bunion record: %w", err)
+	}
+
+	r.PID = int(pid)
+
+	if t, err := time.Parse(DateFormat, r.RawDate); err == nil {
+		r.Date = t
+	}
+
+	if r.Meta == nil {
+		r.Meta = map[string]interface{}{}
+	}
+
+	return r, nil
+}
+
+// Decoder reads @bunion records from a stream, lines that are not records are tolerated.
+type Decoder struct {
+	r       *bufio.Reader
+	lineNum int
+	skipped int
+}
+
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: bufio.NewReaderSize(r, 64*1024)}
+}
+
+// Next reads the next line. If the line is a record, rec is set, otherwise line holds the raw text
+// (without the trailing newline) so that callers can pass it through. err is io.EOF at the end.
+func (d *Decoder) Next() (rec *Record, line []byte, err error) {
+	line, err = d.r.ReadBytes('\n')
+	if len(line) == 0 && err != nil {
+		return nil, nil, err
+	}
+	d.lineNum++
+
+	line = bytes.TrimRight(line, "\r\n")
+
+	rec, perr := Parse(line)
+	if perr != nil {
+		d.skipped++
+		return nil, line, nil
+	}
+
+	return rec, line, nil
+}
+
+// Decode returns the next record, skipping lines that are not records.
+func (d *Decoder) Decode() (*Record, error) {
+	for {
+		rec, _, err := d.Next()
+		if err != nil {
+			return nil, err
+		}
+		if rec != nil {
+			return rec, nil
+		}
+	}
+}
+
+// Buffered is the number of bytes read from the underlying reader but not yet decoded.
+func (d *Decoder) Buffered() int {
+	return d.r.Buffered()
+}
+
+// Line is the number of lines read so far.
+func (d *Decoder) Line() int {
+	return d.lineNum
+}
+
+// Skipped is the number of lines which were not records.
+func (d *Decoder) Skipped() int {
+	return d.skipped
+}
@@ -0,0 +1,137 @@
+package bunion_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oresoftware/json-logging/jlog/bunion"
+	ll "github.com/oresoftware/json-logging/jlog/level"
+	"github.com/oresoftware/json-logging/jlog/lib"
+	"github.com/oresoftware/json-logging/jlog/mult"
+)
+
+func TestRoundTripWithLibEncoder(t *testing.T) {
+	var buf bytes.Buffer
+
+	log := lib.CreateLogger("round-trip").
+		SetOutput(&buf).
+		SetToJSONOutput().
+		SetLogLevel(ll.TRACE).
+		SetHostName("box-1")
+
+	before := time.Now().UTC().Add(-time.Second)
+	log.Warn(lib.MP("requestId", "req-5"), lib.Id("abc"), "hello", 42, true)
+	// what PlainStdout and Spaces write
+	buf.WriteString(`((string) "not a record") ` + "\n")
+	buf.WriteString("   ")
+	log.Info("second")
+
+	d := bunion.NewDecoder(&buf)
+
+	r, err := d.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Version != 1 || r.AppName != "round-trip" || r.Level != "WARN" || r.HostName != "box-1" {
+		t.Fatalf("unexpected header: %+v", r)
+	}
+	if r.PID <= 0 {
+		t.Fatalf("expected a pid, got %d", r.PID)
+	}
+	if r.Date.Before(before) || r.Date.After(time.Now().UTC().Add(time.Second)) {
+		t.Fatalf("unexpected date: %v", r.Date)
+	}
+	if r.Meta["requestId"] != "req-5" || r.LogId() != "abc" || r.LogNum() < 1 {
+		t.Fatalf("unexpected meta: %#v", r.Meta)
+	}
+	if len(r.Args) != 3 || r.Args[0] != "hello" || r.Args[1] != float64(42) || r.Args[2] != true {
+		t.Fatalf("unexpected args: %#v", r.Args)
+	}
+
+	// PlainStdout is skipped, the Spaces prefix is tolerated
+	r2, err := d.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r2.Level != "INFO" || r2.Args[0] != "second" {
+		t.Fatalf("unexpected second record: %+v", r2)
+	}
+	if d.Skipped() != 1 {
+		t.Fatalf("expected one skipped line, got %d", d.Skipped())
+	}
+
+	if _, err := d.Decode(); err != io.EOF {
+		t.Fatalf("expected EOF, got %v", err)
+	}
+
+	// and back again
+	b, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := bunion.Parse(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.RawDate != r.RawDate || again.Meta["requestId"] != "req-5" || len(again.Args) != 3 {
+		t.Fatalf("record did not survive a round trip: %+v", again)
+	}
+}
+
+func TestRoundTripWithMultEncoder(t *testing.T) {
+	var buf bytes.Buffer
+
+	log := mult.New("round-trip-mult", "", []*mult.FileLevel{{
+		Level:  ll.TRACE,
+		Writer: &buf,
+		IsJSON: true,
+	}})
+	log.Error(mult.MP("k", "v"), "boom")
+
+	r, err := bunion.NewDecoder(&buf).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.AppName != "round-trip-mult" || r.Level != "ERROR" || r.Meta["k"] != "v" || r.Args[0] != "boom" {
+		t.Fatalf("unexpected record: %+v", r)
+	}
+}
+
+func TestNextPassesThroughOtherLines(t *testing.T) {
+	input := strings.Join([]string{
+		"plain text",
+		`["@bunion:v2","app","INFO",1,"h","2024-01-02 03:04:05.000006",{},["x"],"extra"]`,
+		`["@bunion:v1","app"]`,
+		`["not bunion"]`,
+		"",
+	}, "\n")
+
+	d := bunion.NewDecoder(strings.NewReader(input))
+
+	rec, line, err := d.Next()
+	if err != nil || rec != nil || string(line) != "plain text" {
+		t.Fatalf("expected a passthrough line, got %v %q %v", rec, line, err)
+	}
+
+	rec, _, err = d.Next()
+	if err != nil || rec == nil || rec.Version != 2 || rec.Args[0] != "x" {
+		t.Fatalf("expected a v2 record, got %+v %v", rec, err)
+	}
+	if rec.Date != time.Date(2024, 1, 2, 3, 4, 5, 6000, time.UTC) {
+		t.Fatalf("unexpected date: %v", rec.Date)
+	}
+
+	for i := 0; i < 2; i++ {
+		if rec, _, err = d.Next(); err != nil || rec != nil {
+			t.Fatalf("expected malformed records to pass through, got %+v %v", rec, err)
+		}
+	}
+
+	if _, _, err := d.Next(); err != io.EOF {
+		t.Fatalf("expected EOF, got %v", err)
+	}
+}
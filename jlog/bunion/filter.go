@@ -0,0 +1,500 @@
+package bunion
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	ll "github.com/oresoftware/json-logging/jlog/level"
+)
+
+// the filter language, eg:
+//
+//   level>=warn app=api requestId=abc
+//   (host~^web- or pid=42) and not msg~timeout
+//   time>=-15m log_num>1000 meta.user.id!=7
+//
+// terms are "field op value" or a bare "field" (true if the field is present).
+// ops are = != > >= < <= ~ (regex) and !~, terms combine with and/&&, or/||, not/!, and parens.
+// terms next to each other are and'ed. values can be quoted with "..." (Go escapes) or '...'.
+//
+// fields: level, app, host, pid, time (or date), msg (the args, space separated), version,
+// anything else is a meta field, dotted names reach into nested objects, and a meta. prefix
+// reaches meta fields named like the builtin ones.
+// times are RFC3339, "2006-01-02 15:04:05" (UTC) or a duration, which means that long ago.
+
+// Filter is a compiled filter expression, the zero value matches everything.
+type Filter struct {
+	src  string
+	root node
+}
+
+// ParseFilter compiles a filter expression, an empty expression matches everything.
+func ParseFilter(expr string) (*Filter, error) {
+	toks, err := lex(expr)
+	if err != nil {
+		return nil, err
+	}
+	var p = &parser{toks: toks, now: time.Now().UTC()}
+	var f = &Filter{src: expr}
+	if len(toks) == 0 {
+		return f, nil
+	}
+	if f.root, err = p.parseOr(); err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.toks) {
+		return nil, p.errorf("unexpected %q", p.toks[p.pos].val)
+	}
+	return f, nil
+}
+
+func (f *Filter) Match(r *Record) bool {
+	if f == nil || f.root == nil {
+		return true
+	}
+	return f.root.match(r)
+}
+
+func (f *Filter) String() string {
+	if f == nil {
+		return ""
+	}
+	return f.src
+}
+
+type node interface {
+	match(r *Record) bool
+}
+
+type andNode struct{ l, r node }
+type orNode struct{ l, r node }
+type notNode struct{ n node }
+type hasNode struct{ field string }
+
+func (n andNode) match(r *Record) bool { return n.l.match(r) && n.r.match(r) }
+func (n orNode) match(r *Record) bool  { return n.l.match(r) || n.r.match(r) }
+func (n notNode) match(r *Record) bool { return !n.n.match(r) }
+
+func (n hasNode) match(r *Record) bool {
+	v, ok := r.Field(n.field)
+	return ok && v != nil
+}
+
+type cmpNode struct {
+	field string
+	op    string
+	val   string
+	num   float64
+	isNum bool
+	re    *regexp.Regexp
+	t     time.Time // for time/date
+	level int       // for level, -1 otherwise
+}
+
+func (n *cmpNode) match(r *Record) bool {
+	v, ok := r.Field(n.field)
+	if !ok || v == nil {
+		// a missing field is never equal to anything
+		return n.op == "!=" || n.op == "!~"
+	}
+
+	if n.re != nil {
+		return n.re.MatchString(FieldString(v)) == (n.op == "~")
+	}
+
+	if n.level >= 0 {
+		return compare(n.op, float64(levelRank(FieldString(v))-n.level))
+	}
+
+	if t, ok := v.(time.Time); ok && !n.t.IsZero() {
+		if t.IsZero() {
+			return n.op == "!="
+		}
+		return compare(n.op, float64(t.Compare(n.t)))
+	}
+
+	if n.isNum {
+		if f, ok := toNumber(v); ok {
+			return compare(n.op, f-n.num)
+		}
+	}
+
+	return compare(n.op, float64(strings.Compare(FieldString(v), n.val)))
+}
+
+func compare(op string, diff float64) bool {
+	switch op {
+	case "=":
+		return diff == 0
+	case "!=":
+		return diff != 0
+	case ">":
+		return diff > 0
+	case ">=":
+		return diff >= 0
+	case "<":
+		return diff < 0
+	case "<=":
+		return diff <= 0
+	}
+	return false
+}
+
+// Field resolves a field name the way filter expressions do, see ParseFilter.
+// Numbers are float64 and time/date is a time.Time.
+func (r *Record) Field(field string) (interface{}, bool) {
+	switch field {
+	case "level":
+		return r.Level, true
+	case "app":
+		return r.AppName, true
+	case "host":
+		return r.HostName, true
+	case "pid":
+		return float64(r.PID), true
+	case "time", "date":
+		return r.Date, true
+	case "version":
+		return float64(r.Version), true
+	case "msg":
+		var parts = make([]string, len(r.Args))
+		for i, a := range r.Args {
+			parts[i] = FieldString(a)
+		}
+		return strings.Join(parts, " "), true
+	}
+
+	field = strings.TrimPrefix(field, "meta.")
+
+	if v, ok := r.Meta[field]; ok {
+		return v, true
+	}
+
+	// nested objects
+	var cur interface{} = r.Meta
+	for _, k := range strings.Split(field, ".") {
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = m[k]; !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// FieldString formats a field value, objects and arrays are JSON.
+func FieldString(v interface{}) string {
+	switch x := v.(type) {
+	case string:
+		return x
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(x)
+	case time.Time:
+		return x.Format(DateFormat)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+func toNumber(v interface{}) (float64, bool) {
+	switch x := v.(type) {
+	case float64:
+		return x, true
+	case string:
+		f, err := strconv.ParseFloat(x, 64)
+		return f, err == nil && !math.IsNaN(f)
+	}
+	return 0, false
+}
+
+// levelRank is the severity of a level name, unknown levels rank below TRACE.
+func levelRank(s string) int {
+	if v, ok := ll.Parse(s); ok {
+		return v.Severity()
+	}
+	return -1
+}
+
+var timeFormats = []string{
+	time.RFC3339Nano,
+	DateFormat,
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+func parseTime(s string, now time.Time) (time.Time, error) {
+	if s == "now" {
+		return now, nil
+	}
+	for _, f := range timeFormats {
+		if t, err := time.Parse(f, s); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	if d, err := time.ParseDuration(strings.TrimPrefix(s, "-")); err == nil {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("json-logging: filter: %q is not a time or a duration", s)
+}
+
+// lexer
+
+type tokKind int
+
+const (
+	tokWord   tokKind = iota
+	tokString tokKind = iota
+	tokOp     tokKind = iota
+	tokAnd    tokKind = iota
+	tokOr     tokKind = iota
+	tokNot    tokKind = iota
+	tokLParen tokKind = iota
+	tokRParen tokKind = iota
+)
+
+type token struct {
+	kind tokKind
+	val  string
+	pos  int
+}
+
+func isWordRune(c rune) bool {
+	return !unicode.IsSpace(c) && !strings.ContainsRune(`()!=<>~&|"'`, c)
+}
+
+func lex(s string) ([]token, error) {
+	var toks []token
+	var rs = []rune(s)
+
+	for i := 0; i < len(rs); {
+		c := rs[i]
+		var next rune
+		if i+1 < len(rs) {
+			next = rs[i+1]
+		}
+
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '(':
+			toks = append(toks, token{tokLParen, "(", i})
+			i++
+		case c == ')':
+			toks = append(toks, token{tokRParen, ")", i})
+			i++
+		case c == '&' && next == '&':
+			toks = append(toks, token{tokAnd, "&&", i})
+			i += 2
+		case c == '|' && next == '|':
+			toks = append(toks, token{tokOr, "||", i})
+			i += 2
+		case c == '!' && (next == '=' || next == '~'):
+			toks = append(toks, token{tokOp, string([]rune{c, next}), i})
+			i += 2
+		case c == '!':
+			toks = append(toks, token{tokNot, "!", i})
+			i++
+		case (c == '<' || c == '>') && next == '=':
+			toks = append(toks, token{tokOp, string([]rune{c, next}), i})
+			i += 2
+		case c == '=' || c == '<' || c == '>' || c == '~':
+			toks = append(toks, token{tokOp, string(c), i})
+			i++
+		case c == '"' || c == '\'':
+			j := i + 1
+			for j < len(rs) && rs[j] != c {
+				if rs[j] == '\\' && c == '"' {
+					j++
+				}
+				j++
+			}
+			if j >= len(rs) {
+				return nil, fmt.Errorf("json-logging: filter: unterminated string at %d", i)
+			}
+			var val = string(rs[i+1 : j])
+			if c == '"' {
+				var err error
+				if val, err = strconv.Unquote(string(rs[i : j+1])); err != nil {
+					return nil, fmt.Errorf("json-logging: filter: bad string at %d: %v", i, err)
+				}
+			}
+			toks = append(toks, token{tokString, val, i})
+			i = j + 1
+		case isWordRune(c):
+			j := i
+			for j < len(rs) && isWordRune(rs[j]) {
+				j++
+			}
+			var word = string(rs[i:j])
+			switch strings.ToLower(word) {
+			case "and":
+				toks = append(toks, token{tokAnd, word, i})
+			case "or":
+				toks = append(toks, token{tokOr, word, i})
+			case "not":
+				toks = append(toks, token{tokNot, word, i})
+			default:
+				toks = append(toks, token{tokWord, word, i})
+			}
+			i = j
+		default:
+			return nil, fmt.Errorf("json-logging: filter: unexpected %q at %d", c, i)
+		}
+	}
+
+	return toks, nil
+}
+
+// parser
+
+type parser struct {
+	toks []token
+	pos  int
+	now  time.Time
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	var at = "the end"
+	if p.pos < len(p.toks) {
+		at = strconv.Itoa(p.toks[p.pos].pos)
+	}
+	return fmt.Errorf("json-logging: filter: %s, at %s", fmt.Sprintf(format, args...), at)
+}
+
+func (p *parser) peek() (token, bool) {
+	if p.pos < len(p.toks) {
+		return p.toks[p.pos], true
+	}
+	return token{}, false
+}
+
+func (p *parser) parseOr() (node, error) {
+	l, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t, ok := p.peek()
+		if !ok || t.kind != tokOr {
+			return l, nil
+		}
+		p.pos++
+		r, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l = orNode{l, r}
+	}
+}
+
+func (p *parser) parseAnd() (node, error) {
+	l, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t, ok := p.peek()
+		if !ok || t.kind == tokOr || t.kind == tokRParen {
+			return l, nil
+		}
+		if t.kind == tokAnd {
+			p.pos++
+		}
+		r, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		l = andNode{l, r}
+	}
+}
+
+func (p *parser) parseUnary() (node, error) {
+	t, ok := p.peek()
+	if !ok {
+		return nil, p.errorf("expected a term")
+	}
+
+	switch t.kind {
+	case tokNot:
+		p.pos++
+		n, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{n}, nil
+	case tokLParen:
+		p.pos++
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if t, ok := p.peek(); !ok || t.kind != tokRParen {
+			return nil, p.errorf("expected )")
+		}
+		p.pos++
+		return n, nil
+	case tokWord, tokString:
+		return p.parseTerm()
+	}
+
+	return nil, p.errorf("unexpected %q", t.val)
+}
+
+func (p *parser) parseTerm() (node, error) {
+	var field = p.toks[p.pos].val
+	p.pos++
+
+	op, ok := p.peek()
+	if !ok || op.kind != tokOp {
+		return hasNode{field}, nil
+	}
+	p.pos++
+
+	val, ok := p.peek()
+	if !ok || (val.kind != tokWord && val.kind != tokString) {
+		return nil, p.errorf("expected a value after %s%s", field, op.val)
+	}
+	p.pos++
+
+	var n = &cmpNode{field: field, op: op.val, val: val.val, level: -1}
+
+	switch {
+	case op.val == "~" || op.val == "!~":
+		re, err := regexp.Compile(val.val)
+		if err != nil {
+			return nil, fmt.Errorf("json-logging: filter: bad regex %q: %v", val.val, err)
+		}
+		n.re = re
+	case field == "level":
+		if n.level = levelRank(val.val); n.level < 0 {
+			return nil, fmt.Errorf("json-logging: filter: unknown level %q", val.val)
+		}
+	case field == "time" || field == "date":
+		t, err := parseTime(val.val, p.now)
+		if err != nil {
+			return nil, err
+		}
+		n.t = t
+	default:
+		if f, err := strconv.ParseFloat(val.val, 64); err == nil {
+			n.num, n.isNum = f, true
+		}
+	}
+
+	return n, nil
+}
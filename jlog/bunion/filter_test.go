@@ -0,0 +1,95 @@
+package bunion_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/oresoftware/json-logging/jlog/bunion"
+)
+
+func TestFilter(t *testing.T) {
+	rec, err := bunion.Parse([]byte(`["@bunion:v1","api","WARN",42,"web-1","2024-01-02 03:04:05.000006",` +
+		`{"log_num":1500,"requestId":"abc","user":{"id":7},"app":"shadowed"},["request timed out",3]]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		expr  string
+		match bool
+	}{
+		{"", true},
+		{"level>=warn", true},
+		{"level>=ERROR", false},
+		{"level=Warn", true},
+		{"level<info", false},
+		{"app=api", true},
+		{"app!=api", false},
+		{"meta.app=shadowed", true},
+		{"host~^web-", true},
+		{"host!~^web-", false},
+		{"pid=42", true},
+		{"requestId=abc", true},
+		{`requestId="abc"`, true},
+		{"requestId='xyz'", false},
+		{"log_num>1000", true},
+		{"log_num<=1000", false},
+		{"user.id=7", true},
+		{"user.name=bob", false},
+		{"user.name!=bob", true},
+		{"requestId", true},
+		{"missing", false},
+		{"!missing", true},
+		{`msg~"timed out 3$"`, true},
+		{"time>='2024-01-02 03:00'", true},
+		{"time<2024-01-02T03:04:05Z", false},
+		{"time>=1h", false},
+		{"level>=warn app=api", true},
+		{"level>=warn and app=worker", false},
+		{"app=worker or requestId=abc", true},
+		{"app=worker || (log_num>1000 && not host=web-2)", true},
+		{"not (app=api or app=worker)", false},
+	}
+
+	for _, c := range cases {
+		f, err := bunion.ParseFilter(c.expr)
+		if err != nil {
+			t.Errorf("%q: %v", c.expr, err)
+			continue
+		}
+		if f.Match(rec) != c.match {
+			t.Errorf("%q: expected match=%v", c.expr, c.match)
+		}
+	}
+}
+
+func TestFilterRelativeTime(t *testing.T) {
+	rec := &bunion.Record{Version: 1, Level: "INFO", Date: time.Now().UTC().Add(-time.Minute), Meta: map[string]interface{}{}}
+
+	for expr, match := range map[string]bool{"time>=5m": true, "time>=-30s": false, "time<now": true} {
+		f, err := bunion.ParseFilter(expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f.Match(rec) != match {
+			t.Errorf("%q: expected match=%v", expr, match)
+		}
+	}
+}
+
+func TestFilterErrors(t *testing.T) {
+	for _, expr := range []string{
+		"level>=nope",
+		"app=",
+		"(app=api",
+		"app=api)",
+		"time>yesterday",
+		"msg~(",
+		`app="unterminated`,
+		"and",
+	} {
+		if _, err := bunion.ParseFilter(expr); err == nil {
+			t.Errorf("%q: expected an error", expr)
+		}
+	}
+}
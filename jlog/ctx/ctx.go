@@ -0,0 +1,140 @@
+package jctx
+
+import (
+	"context"
+	"sync"
+
+	"github.com/oresoftware/json-logging/jlog/shared"
+)
+
+type loggerKey struct{}
+type metaKey struct{}
+type requestIdKey struct{}
+type traceIdKey struct{}
+type userIdKey struct{}
+
+// Extractor pulls a single value out of a context, which ends up in the meta map under key.
+// ok=false means the context does not have the value and nothing is added.
+type Extractor func(ctx context.Context) (key string, val interface{}, ok bool)
+
+var mtx = sync.RWMutex{}
+
+var extractors = []Extractor{
+	ValueExtractor("request_id", requestIdKey{}),
+	ValueExtractor("trace_id", traceIdKey{}),
+	ValueExtractor("user_id", userIdKey{}),
+}
+
+// RegisterExtractor adds an extractor that is run against the context on every *Ctx logging call.
+func RegisterExtractor(e Extractor) {
+	if e == nil {
+		return
+	}
+	mtx.Lock()
+	defer mtx.Unlock()
+	extractors = append(extractors, e)
+}
+
+// ValueExtractor creates an extractor for values stored with context.WithValue(ctx, ctxKey, v).
+func ValueExtractor(metaKey string, ctxKey interface{}) Extractor {
+	return func(ctx context.Context) (string, interface{}, bool) {
+		v := ctx.Value(ctxKey)
+		if v == nil {
+			return "", nil, false
+		}
+		return metaKey, v, true
+	}
+}
+
+func WithRequestId(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIdKey{}, id)
+}
+
+func WithTraceId(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, traceIdKey{}, id)
+}
+
+func WithUserId(ctx context.Context, id interface{}) context.Context {
+	return context.WithValue(ctx, userIdKey{}, id)
+}
+
+func RequestId(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	v, _ := ctx.Value(requestIdKey{}).(string)
+	return v
+}
+
+func TraceId(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	v, _ := ctx.Value(traceIdKey{}).(string)
+	return v
+}
+
+// WithLogger stashes a logger (usually a Child logger) in the context.
+func WithLogger(ctx context.Context, l shared.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// LoggerFrom returns the logger stashed by WithLogger, or nil.
+func LoggerFrom(ctx context.Context) shared.Logger {
+	if ctx == nil {
+		return nil
+	}
+	l, _ := ctx.Value(loggerKey{}).(shared.Logger)
+	return l
+}
+
+// WithMetaFields stashes meta fields in the context, merged on top of any fields already there,
+// a nil ctx is treated as context.Background().
+func WithMetaFields(ctx context.Context, mf *shared.MetaFields) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	var m = shared.MF{}
+	if existing, ok := ctx.Value(metaKey{}).(*shared.MetaFields); ok && existing.Map() != nil {
+		for k, v := range *existing.Map() {
+			m[k] = v
+		}
+	}
+	if mf != nil && mf.Map() != nil {
+		for k, v := range *mf.Map() {
+			m[k] = v
+		}
+	}
+	return context.WithValue(ctx, metaKey{}, shared.NewMetaFields(&m))
+}
+
+// WithMetaPairs is shorthand for WithMetaFields(ctx, shared.MP(...)).
+func WithMetaPairs(ctx context.Context, k1 string, v1 interface{}, args ...interface{}) context.Context {
+	return WithMetaFields(ctx, shared.MP(k1, v1, args...))
+}
+
+// MetaFields returns the stashed meta fields plus the values found by the registered extractors.
+func MetaFields(ctx context.Context) *shared.MetaFields {
+	var m = shared.MF{}
+
+	if ctx == nil {
+		return shared.NewMetaFields(&m)
+	}
+
+	if existing, ok := ctx.Value(metaKey{}).(*shared.MetaFields); ok && existing.Map() != nil {
+		for k, v := range *existing.Map() {
+			m[k] = v
+		}
+	}
+
+	mtx.RLock()
+	defer mtx.RUnlock()
+
+	for _, e := range extractors {
+		if k, v, ok := e(ctx); ok {
+			m[k] = v
+		}
+	}
+
+	return shared.NewMetaFields(&m)
+}
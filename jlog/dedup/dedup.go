@@ -0,0 +1,136 @@
+package dedup
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	ll "github.com/oresoftware/json-logging/jlog/level"
+)
+
+// meta fields which differ between otherwise identical records
+var ignoredMeta = map[string]bool{"log_num": true}
+
+type Params struct {
+	Window time.Duration // repeats are swallowed for this long after a record is written, 5s if 0
+}
+
+// Repeat is a record that was repeated, and swallowed, Count times.
+type Repeat struct {
+	Level ll.LogLevel
+	Meta  map[string]interface{} // the meta of the repeated record
+	Count int
+	First time.Time // when the repeated record was written
+	Last  time.Time // when the last repeat was swallowed
+}
+
+// Message is the text of the follow-up record.
+func (r Repeat) Message() string {
+	var times = "times"
+	if r.Count == 1 {
+		times = "time"
+	}
+	return fmt.Sprintf("previous message repeated %d %s over %s", r.Count, times, formatDuration(r.Last.Sub(r.First)))
+}
+
+func formatDuration(d time.Duration) string {
+	switch {
+	case d >= time.Second:
+		return d.Round(100 * time.Millisecond).String()
+	case d >= time.Millisecond:
+		return d.Round(time.Millisecond).String()
+	}
+	return d.String()
+}
+
+// Deduper swallows consecutive identical records, it is shared by a logger and its children.
+type Deduper struct {
+	mtx      sync.Mutex
+	p        Params
+	onRepeat func(Repeat)
+	lastKey  string
+	last     Repeat
+	timer    *time.Timer
+	now      func() time.Time
+}
+
+// NewDeduper creates a deduper, onRepeat is called with the repeats of a record before the next
+// different record is written, or when the window runs out (from a timer goroutine).
+func NewDeduper(p Params, onRepeat func(Repeat)) *Deduper {
+	if p.Window <= 0 {
+		p.Window = 5 * time.Second
+	}
+	return &Deduper{p: p, onRepeat: onRepeat, now: time.Now}
+}
+
+// Fingerprint identifies a record by its level, meta and args, ok is false if it cannot be marshalled.
+func Fingerprint(level ll.LogLevel, meta map[string]interface{}, args []interface{}) (string, bool) {
+	var m = make(map[string]interface{}, len(meta))
+	for k, v := range meta {
+		if !ignoredMeta[k] {
+			m[k] = v
+		}
+	}
+	b, err := json.Marshal([]interface{}{int(level), m, args})
+	if err != nil {
+		return "", false
+	}
+	return string(b), true
+}
+
+// Allow is false if the record repeats the previous one within the window.
+func (d *Deduper) Allow(level ll.LogLevel, meta map[string]interface{}, args []interface{}) bool {
+	key, ok := Fingerprint(level, meta, args)
+
+	d.mtx.Lock()
+	var now = d.now()
+
+	if ok && key == d.lastKey && now.Sub(d.last.First) < d.p.Window {
+		d.last.Count++
+		d.last.Last = now
+		if d.timer == nil {
+			d.timer = time.AfterFunc(d.last.First.Add(d.p.Window).Sub(now), d.Flush)
+		}
+		d.mtx.Unlock()
+		return false
+	}
+
+	var pending = d.takeRepeat()
+	if ok {
+		d.lastKey = key
+		d.last = Repeat{Level: level, Meta: meta, First: now}
+	}
+	d.mtx.Unlock()
+
+	if pending != nil && d.onRepeat != nil {
+		d.onRepeat(*pending)
+	}
+	return true
+}
+
+// takeRepeat returns the pending repeats, if any, and forgets the last record
+func (d *Deduper) takeRepeat() *Repeat {
+	if d.timer != nil {
+		d.timer.Stop()
+		d.timer = nil
+	}
+	var r = d.last
+	d.lastKey = ""
+	d.last = Repeat{}
+	if r.Count < 1 {
+		return nil
+	}
+	return &r
+}
+
+// Flush logs the pending repeats now, the next identical record is written again.
+func (d *Deduper) Flush() {
+	d.mtx.Lock()
+	var pending = d.takeRepeat()
+	d.mtx.Unlock()
+
+	if pending != nil && d.onRepeat != nil {
+		d.onRepeat(*pending)
+	}
+}
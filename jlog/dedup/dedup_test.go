@@ -0,0 +1,61 @@
+package dedup
+
+import (
+	"testing"
+	"time"
+
+	ll "github.com/oresoftware/json-logging/jlog/level"
+)
+
+func TestConsecutiveRepeatsAreSwallowed(t *testing.T) {
+	var repeats []Repeat
+	var d = NewDeduper(Params{Window: 5 * time.Second}, func(r Repeat) {
+		repeats = append(repeats, r)
+	})
+	var now = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	d.now = func() time.Time { return now }
+
+	var meta = func(n int) map[string]interface{} {
+		return map[string]interface{}{"app": "x", "log_num": n}
+	}
+
+	var written = 0
+	for i := 0; i < 483; i++ {
+		if d.Allow(ll.WARN, meta(i), []interface{}{"retrying", 1}) {
+			written++
+		}
+		now = now.Add(10 * time.Millisecond)
+	}
+	if written != 1 {
+		t.Fatalf("expected one record to be written, got %d", written)
+	}
+
+	if !d.Allow(ll.WARN, meta(0), []interface{}{"retrying", 2}) {
+		t.Fatal("expected a different record to be written")
+	}
+	if len(repeats) != 1 || repeats[0].Count != 482 || repeats[0].Level != ll.WARN || repeats[0].Meta["app"] != "x" {
+		t.Fatalf("unexpected repeats: %+v", repeats)
+	}
+	if m := repeats[0].Message(); m != "previous message repeated 482 times over 4.8s" {
+		t.Fatalf("unexpected message: %q", m)
+	}
+
+	if !d.Allow(ll.ERROR, meta(0), []interface{}{"retrying", 2}) {
+		t.Fatal("expected the level to be part of the fingerprint")
+	}
+
+	d.Allow(ll.ERROR, meta(0), []interface{}{"retrying", 2})
+	now = now.Add(6 * time.Second)
+	if !d.Allow(ll.ERROR, meta(0), []interface{}{"retrying", 2}) {
+		t.Fatal("expected the record to be written again after the window")
+	}
+	if len(repeats) != 2 || repeats[1].Count != 1 || repeats[1].Message() != "previous message repeated 1 time over 0s" {
+		t.Fatalf("unexpected repeats: %+v", repeats)
+	}
+
+	d.Allow(ll.ERROR, meta(0), []interface{}{"retrying", 2})
+	d.Flush()
+	if len(repeats) != 3 {
+		t.Fatalf("expected Flush to report the pending repeats: %+v", repeats)
+	}
+}
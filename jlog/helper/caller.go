@@ -0,0 +1,85 @@
+package hlpr
+
+import (
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// Caller is the source location of a logging call, it goes into the meta map under "caller".
+type Caller struct {
+	File string `json:"file"`
+	Line int    `json:"line"`
+	Func string `json:"func"`
+}
+
+func (c *Caller) String() string {
+	return c.File + ":" + strconv.Itoa(c.Line)
+}
+
+// ShortFile is the file with only its parent directory, eg lib/lib.go.
+func (c *Caller) ShortFile() string {
+	return filepath.Join(filepath.Base(filepath.Dir(c.File)), filepath.Base(c.File))
+}
+
+// IsLibraryFrame is true for frames that belong to json-logging itself (or to log/slog, when
+// logging through the slog handler), those are skipped when looking for the caller.
+func IsLibraryFrame(function string, file string) bool {
+	return strings.Contains(function, "oresoftware/json-logging/jlog/") ||
+		strings.HasPrefix(function, "log/slog.") ||
+		strings.HasPrefix(function, "runtime.")
+}
+
+// GetCaller returns the first frame outside of json-logging, or nil.
+// skip is the number of frames outside of json-logging to pass over first, eg for logging helpers.
+func GetCaller(skip int) *Caller {
+	var pcs [32]uintptr
+	n := runtime.Callers(2, pcs[:])
+	frames := runtime.CallersFrames(pcs[:n])
+
+	for {
+		f, more := frames.Next()
+		if !IsLibraryFrame(f.Function, f.File) && f.File != "" {
+			if skip < 1 {
+				return &Caller{File: f.File, Line: f.Line, Func: f.Function}
+			}
+			skip--
+		}
+		if !more {
+			return nil
+		}
+	}
+}
+
+// CallerFromPC returns the frame of a program counter, eg slog.Record.PC, or nil.
+func CallerFromPC(pc uintptr) *Caller {
+	if pc == 0 {
+		return nil
+	}
+	f, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	if f.File == "" {
+		return nil
+	}
+	return &Caller{File: f.File, Line: f.Line, Func: f.Function}
+}
+
+// CallerFromMeta reads the caller back out of a meta map, it is a *Caller when logging,
+// and a map after a @bunion record has been decoded.
+func CallerFromMeta(v interface{}) (*Caller, bool) {
+	switch c := v.(type) {
+	case *Caller:
+		return c, c != nil
+	case Caller:
+		return &c, true
+	case map[string]interface{}:
+		file, _ := c["file"].(string)
+		line, _ := c["line"].(float64)
+		fn, _ := c["func"].(string)
+		if file == "" {
+			return nil, false
+		}
+		return &Caller{File: file, Line: int(line), Func: fn}, true
+	}
+	return nil, false
+}
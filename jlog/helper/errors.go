@@ -0,0 +1,201 @@
+package hlpr
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	au "github.com/oresoftware/json-logging/jlog/au"
+)
+
+const maxErrorDepth = 32
+
+// ErrorMarker is the first element of an error in the JSON args, it tells errors apart from other arrays.
+const ErrorMarker = "@error"
+
+// ErrorInfo is a logged error with its cause chain, errors.Join and Unwrap() []error give several causes.
+// In JSON it is a nested array, eg ["@error", "*fs.PathError", "stat /x: no such file", {"Op": "stat"}, [<causes>]]
+type ErrorInfo struct {
+	Type    string
+	Message string
+	Fields  map[string]interface{}
+	Causes  []*ErrorInfo
+}
+
+func (e *ErrorInfo) MarshalJSON() ([]byte, error) {
+	var causes = e.Causes
+	if causes == nil {
+		causes = []*ErrorInfo{}
+	}
+	return json.Marshal([5]interface{}{ErrorMarker, e.Type, e.Message, e.Fields, causes})
+}
+
+// NewErrorInfo expands an error into its cause chain, it returns nil for a nil error.
+func NewErrorInfo(err error) *ErrorInfo {
+	return newErrorInfo(err, 0)
+}
+
+func isNilError(err error) bool {
+	if err == nil {
+		return true
+	}
+	rv := reflect.ValueOf(err)
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface, reflect.Func, reflect.Chan:
+		return rv.IsNil()
+	}
+	return false
+}
+
+func newErrorInfo(err error, depth int) *ErrorInfo {
+	if isNilError(err) {
+		return nil
+	}
+
+	var e = &ErrorInfo{
+		Type:    fmt.Sprintf("%T", err),
+		Message: err.Error(),
+		Fields:  errorFields(err),
+	}
+
+	if depth >= maxErrorDepth {
+		return e
+	}
+
+	var causes []error
+	switch x := err.(type) {
+	case interface{ Unwrap() []error }:
+		causes = x.Unwrap()
+	case interface{ Unwrap() error }:
+		causes = []error{x.Unwrap()}
+	}
+
+	for _, c := range causes {
+		if ci := newErrorInfo(c, depth+1); ci != nil {
+			e.Causes = append(e.Causes, ci)
+		}
+	}
+
+	return e
+}
+
+// errorFields are the exported fields of a struct error, eg Op and Path of *os.PathError.
+// Fields holding errors are left out, they show up as causes.
+func errorFields(err error) map[string]interface{} {
+	rv := reflect.ValueOf(err)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var errorType = reflect.TypeOf((*error)(nil)).Elem()
+	var fields map[string]interface{}
+	typ := rv.Type()
+
+	for i := 0; i < rv.NumField(); i++ {
+		f := typ.Field(i)
+		if !f.IsExported() || f.Type.Implements(errorType) {
+			continue
+		}
+		if f.Type.Kind() == reflect.Slice && f.Type.Elem().Implements(errorType) {
+			continue
+		}
+		v := rv.Field(i).Interface()
+		if _, err := json.Marshal(v); err != nil {
+			v = fmt.Sprintf("%v", v)
+		}
+		if fields == nil {
+			fields = map[string]interface{}{}
+		}
+		fields[f.Name] = v
+	}
+
+	return fields
+}
+
+// ErrorFromArg returns the error info of an arg, which is an *ErrorInfo when logging,
+// and an array starting with ErrorMarker after a @bunion record has been decoded.
+func ErrorFromArg(v interface{}) (*ErrorInfo, bool) {
+	switch x := v.(type) {
+	case *ErrorInfo:
+		return x, x != nil
+	case []interface{}:
+		return errorFromArray(x)
+	}
+	return nil, false
+}
+
+func errorFromArray(a []interface{}) (*ErrorInfo, bool) {
+	if len(a) != 5 || a[0] != ErrorMarker {
+		return nil, false
+	}
+	typ, ok1 := a[1].(string)
+	msg, ok2 := a[2].(string)
+	if !ok1 || !ok2 {
+		return nil, false
+	}
+
+	var e = &ErrorInfo{Type: typ, Message: msg}
+	e.Fields, _ = a[3].(map[string]interface{})
+
+	causes, _ := a[4].([]interface{})
+	for _, c := range causes {
+		ca, ok := c.([]interface{})
+		if !ok {
+			return nil, false
+		}
+		ci, ok := errorFromArray(ca)
+		if !ok {
+			return nil, false
+		}
+		e.Causes = append(e.Causes, ci)
+	}
+
+	return e, true
+}
+
+// PrettyError renders the error on the current line and its causes as an indented tree below it.
+func PrettyError(e *ErrorInfo) string {
+	var b strings.Builder
+	writeError(&b, e, 0, "")
+	return b.String()
+}
+
+func writeError(b *strings.Builder, e *ErrorInfo, depth int, label string) {
+	if depth > 0 {
+		b.WriteString("\n")
+		b.WriteString(strings.Repeat("    ", depth))
+		b.WriteString(au.Col.Faint(label).String())
+	}
+
+	// errors.Join messages are one per line, which would break the tree
+	b.WriteString(au.Col.Red(strings.ReplaceAll(e.Message, "\n", "; ")).String())
+	b.WriteString(" ")
+	b.WriteString(au.Col.Faint("(" + e.Type + ")").String())
+
+	if len(e.Fields) > 0 {
+		var keys = make([]string, 0, len(e.Fields))
+		for k := range e.Fields {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			b.WriteString(fmt.Sprintf(" %s%v", au.Col.Gray(12, k+"=").String(), e.Fields[k]))
+		}
+	}
+
+	var label2 = "caused by: "
+	if len(e.Causes) > 1 {
+		label2 = "joined: "
+	}
+	for _, c := range e.Causes {
+		writeError(b, c, depth+1, label2)
+	}
+}
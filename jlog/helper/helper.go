@@ -8,7 +8,6 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
-	"runtime"
 	"runtime/debug"
 	"strconv"
 	"strings"
@@ -1168,32 +1167,14 @@ func CleanUp(v interface{}, cache Cache) (z interface{}) {
 	return fmt.Sprintf("unknown type: %v", v)
 }
 
+// GetFilteredStacktrace returns the stack as "function file:line" lines, without json-logging's frames.
+// Use GetStackFrames for structured frames.
 func GetFilteredStacktrace() *[]string {
-	// Capture the stack trace
-	buf := make([]byte, 1024)
-	n := runtime.Stack(buf, false)
-	stackTrace := string(buf[:n])
-
-	// Filter the stack trace
-	lines := strings.Split(stackTrace, "\n")
-	var filteredLines = []string{}
-	for _, line := range lines {
-
-		if strings.Contains(line, "oresoftware/json-logging") {
-			continue
-		}
-
-		if !strings.Contains(line, ".go:") {
-			continue
-		}
-
-		var nl = fmt.Sprintf("%s", strings.TrimSpace(line))
-		if len(nl) > 0 {
-			filteredLines = append(filteredLines, nl)
-		}
+	var lines = []string{}
+	for _, f := range GetStackFrames(1, StackParams{}) {
+		lines = append(lines, f.String())
 	}
-
-	return &filteredLines
+	return &lines
 }
 
 func OpenFile(fp string) (*os.File, error) {
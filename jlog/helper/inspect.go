@@ -0,0 +1,433 @@
+package hlpr
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+)
+
+// Inspect converts v the way pretty output does, eg for a recovered panic value, so that values
+// such as structs with unexported fields do not serialize as {}.
+func Inspect(v interface{}) interface{} {
+	return GetInspectableVal(v, reflect.ValueOf(v), 0, 1)
+}
+
+type ArrayVal struct {
+	GoType      string
+	TrueLen     int
+	IsTruncated bool
+	Val         []interface{}
+}
+
+type MapVal struct {
+	GoType       string
+	TrueKeyCount int
+	IsTruncated  bool
+	Val          map[string]interface{}
+}
+
+type EmptyVal struct {
+	EmptyVal bool
+}
+
+func doMap(v interface{}, val reflect.Value) *MapVal {
+
+	if x, ok := v.(MapVal); ok {
+		return &x
+	}
+
+	if x, ok := v.(*MapVal); ok {
+		return x
+	}
+
+	if !val.IsValid() {
+		return nil
+	}
+
+	var z = MapVal{
+		GoType:       "<unknown>",
+		TrueKeyCount: 0,
+		IsTruncated:  false,
+		Val:          nil,
+	}
+
+	len := val.Len()
+	z.TrueKeyCount = len
+	z.GoType = val.Type().String()
+
+	keyToRetrieve := "JLogMarker"
+	keyType := val.Type().Key()
+	markerKey := reflect.ValueOf(keyToRetrieve)
+
+	if markerKey.Type().AssignableTo(keyType) {
+		keyValue := val.MapIndex(markerKey)
+		if keyValue.IsValid() {
+			return &z
+		}
+	} else if markerKey.Type().ConvertibleTo(keyType) {
+		keyValue := val.MapIndex(markerKey.Convert(keyType))
+		if keyValue.IsValid() {
+			return &z
+		}
+	}
+
+	keys := val.MapKeys()
+
+	min := int(math.Min(float64(len), float64(25)))
+	if min < len {
+		z.IsTruncated = true
+	}
+
+	z.Val = map[string]interface{}{}
+
+	i := 0
+	for _, key := range keys {
+		if i++; i > min {
+			break
+		}
+		var el = val.MapIndex(key)
+		if el.IsValid() && el.CanInterface() {
+			z.Val[fmt.Sprintf("%v", key)] = GetInspectableVal(el.Interface(), el, 0, 1)
+		} else {
+			z.Val[fmt.Sprintf("%v", key)] = nil
+		}
+	}
+
+	// for i := 0; i < min; i++ {
+	//  el := val.Index(i)
+	//  if el.IsValid() {
+	//    z.Val[i] = GetInspectableVal(el.Interface(), el, 0, 1)
+	//  } else {
+	//    // Handle the case where the value is nil
+	//    z.Val[i] = nil // or any default value you want
+	//  }
+	// }
+
+	return &z
+}
+
+func doArray(v interface{}, rv reflect.Value) *ArrayVal {
+
+	if x, ok := v.(ArrayVal); ok {
+		return &x
+	}
+
+	if x, ok := v.(*ArrayVal); ok {
+		return x
+	}
+
+	var z = ArrayVal{
+		TrueLen:     0,
+		IsTruncated: false,
+		Val:         nil,
+		GoType:      "<unknown>",
+	}
+
+	len := rv.Len()
+	z.TrueLen = len
+
+	min := int(math.Min(float64(len), float64(40)))
+	if min < len {
+		z.IsTruncated = true
+	}
+
+	z.Val = make([]interface{}, min)
+
+	for i := 0; i < min; i++ {
+		el := rv.Index(i)
+		if el.IsValid() {
+			z.GoType = fmt.Sprintf("%s", el.Type().String())
+			inf := el.Interface()
+			// z.GoType = fmt.Sprintf("%T", inf)
+			z.Val[i] = GetInspectableVal(inf, el, 0, 1)
+		} else {
+			// Handle the case where the value is nil
+			z.Val[i] = nil // or any default value you want
+		}
+	}
+
+	// TODO: add the 3 last original elements to end of new list, if space permits
+
+	// for i := 0; i < 3; i++ {
+	//  z.Val = append(z.Val, EmptyVal{EmptyVal: true})
+	// }
+	//
+	// var b = math.Max(3, float64(min-len))
+	//
+	// for i := int(b); i >= 0; i-- {
+	//  z.Val = append(z.Val, GetInspectableVal(rv.Index(len-1-i).Interface(), 0))
+	// }
+
+	return &z
+}
+
+type VibeInspectStr interface {
+	ToString() string
+}
+
+type VibeInspectInt interface {
+	ToInt() int
+}
+
+type VibeInspectBool interface {
+	ToBool() bool
+}
+
+type UnkVal struct {
+	GoType   string
+	Val      interface{}
+	ValAsStr string
+}
+
+// GetInspectableVal converts a value with reflection, so that maps, slices and structs with unexported
+// fields can be logged, it is what pretty output and Inspect use.
+func GetInspectableVal(obj interface{}, rv reflect.Value, depth int, count int) interface{} {
+	// /
+	// var rv = reflect.ValueOf(obj)
+
+	if depth > 16 {
+		return fmt.Sprintf("(go:max-depth:%T)", obj)
+	}
+
+	if count > 11 {
+		return obj
+	}
+
+	if !rv.IsValid() {
+		// Handle invalid reflection value (e.g., nil pointer)
+		return nil
+	}
+
+	var v = rv.Interface()
+
+	if v == nil {
+		return v
+	}
+
+	if err, ok := v.(error); ok {
+		// the cause chain, instead of only Error()
+		if e := NewErrorInfo(err); e != nil {
+			return e
+		}
+		return nil
+	}
+
+	if rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+
+		if rv.IsNil() {
+			// Handle nil interface value
+			return nil
+		}
+
+		if !rv.IsValid() {
+			return nil
+		}
+
+		rv = rv.Elem()
+
+		if !rv.IsValid() {
+			return nil
+		}
+
+		if rv.CanInterface() {
+			return GetInspectableVal(rv.Interface(), rv, depth, count+1)
+		}
+
+	}
+
+	if !rv.CanInterface() {
+		return v
+	}
+
+	v = rv.Interface()
+
+	if v == nil {
+		return nil
+	}
+
+	if x, ok := v.(string); ok {
+		return x
+	}
+
+	if x, ok := v.(int); ok {
+		return x
+	}
+
+	if x, ok := v.(bool); ok {
+		return x
+	}
+
+	// if depth > 3 {
+	//  return v
+	// }
+
+	if !rv.IsValid() {
+		return nil
+	}
+
+	switch rv.Kind() {
+	//
+	case reflect.Slice:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			if z, ok := v.([]byte); ok {
+				return string(z)
+			}
+			return v
+		}
+		return doArray(v, rv)
+
+	case reflect.Array:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			if z, ok := v.([]byte); ok {
+				return string(z)
+			}
+			return v
+		}
+		return doArray(v, rv)
+	}
+
+	if rv.Kind() == reflect.Func {
+		return fmt.Sprintf("(func())")
+	}
+
+	if rv.Kind() == reflect.Chan {
+		if rv.IsValid() && rv.CanInterface() {
+			return fmt.Sprintf("(chan (%v) %v)", rv.Type(), rv.Interface())
+		}
+		return fmt.Sprintf("(chan (%v) (%v) %+v)", rv.Type(), v, v)
+	}
+
+	if rv.Kind() == reflect.Map {
+		return doMap(v, rv)
+	}
+
+	if rv.Kind() != reflect.Struct {
+		// if it's a not a struct now
+		var str = fmt.Sprintf("(%v / %v)", v, rv.Type().String())
+		var t = fmt.Sprintf("(%T / %v)", v, rv.Type().String())
+		return &UnkVal{
+			GoType:   t,
+			Val:      v,
+			ValAsStr: str,
+		}
+	}
+
+	// it's a struct, so we can add metadata to it
+	var errStr = ""
+	var toString = ""
+
+	var typeStr = fmt.Sprintf("%T", v)
+
+	if rv.IsValid() {
+		typ := rv.Type()
+		z := typ.String()
+		if z != typeStr {
+			typeStr = fmt.Sprintf("(%s / %v / %s)", typeStr, typ, z)
+		}
+	}
+
+	if z, ok := v.(error); ok {
+		errStr = z.Error()
+	}
+
+	if z, ok := v.(Stringer); ok {
+		toString = z.String()
+	}
+
+	outResult := make(map[string]interface{})
+
+	if typeStr != "" {
+		outResult["+(GoType):"] = typeStr
+	}
+
+	if errStr != "" {
+		outResult["+(ErrStr):"] = errStr
+	}
+
+	if toString != "" && toString != errStr {
+		outResult["+(ToStr):"] = toString
+	}
+
+	innerResult := make(map[string]interface{})
+	outResult["+(Val):"] = innerResult
+
+	typ := rv.Type()
+
+	for i := 0; i < rv.NumField(); i++ {
+
+		field := rv.Field(i)
+		fieldName := typ.Field(i).Name
+
+		if !field.IsValid() {
+			innerResult[fieldName] = nil
+			continue
+		}
+
+		j := 0
+
+		for {
+
+			if j++; j > 9 {
+				// only try to deref so many times - perhaps it's a ptr to a ptr, etc
+				break
+			}
+
+			if !(field.Kind() == reflect.Ptr || field.Kind() == reflect.Interface) {
+				break
+			}
+
+			if !field.IsValid() {
+				innerResult[fieldName] = nil
+				break
+			}
+
+			if field.IsNil() {
+				innerResult[fieldName] = nil
+				break
+			}
+
+			field = field.Elem()
+
+			if !field.IsValid() {
+				innerResult[fieldName] = nil
+				break
+			}
+
+		}
+
+		if _, ok := innerResult[fieldName]; ok {
+			continue
+		}
+
+		if field.Kind() == reflect.Interface || field.Kind() == reflect.Ptr {
+
+			if field.IsNil() {
+				innerResult[fieldName] = nil
+				continue
+			}
+
+			if !field.IsValid() {
+				innerResult[fieldName] = nil
+				continue
+			}
+
+			field = field.Elem()
+
+			if !field.IsValid() {
+				innerResult[fieldName] = nil
+				continue
+			}
+
+			return fmt.Sprintf("yyy %T // %v", field.Interface(), field.Interface())
+		}
+
+		if field.CanInterface() {
+			innerResult[fieldName] = GetInspectableVal(field.Interface(), field, depth+1, 1)
+			continue
+		}
+
+		innerResult[fieldName] = fmt.Sprintf("%v (Type: %s)", field.String(), field.Type().String())
+	}
+
+	return outResult
+}
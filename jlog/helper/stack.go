@@ -0,0 +1,148 @@
+package hlpr
+
+import (
+	au "github.com/oresoftware/json-logging/jlog/au"
+	"path"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+const DefaultStackDepth = 64
+
+// Frame is a single stack frame, as attached to records by Error/Critical.
+type Frame struct {
+	Function string `json:"function"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Package  string `json:"package"`
+}
+
+func (f Frame) String() string {
+	return f.Function + " " + f.File + ":" + strconv.Itoa(f.Line)
+}
+
+// StackParams controls which frames end up in a stack trace.
+// json-logging's own frames (and the runtime's) are always left out.
+type StackParams struct {
+	MaxDepth int      // the max number of frames kept after filtering, 0 means DefaultStackDepth
+	Include  []string // if not empty, only frames from packages matching one of these are kept
+	Exclude  []string // frames from packages matching one of these are left out
+	Skip     int      // the number of frames dropped from the top, eg for logging helper functions
+}
+
+// patterns are package paths, "github.com/foo/..." matches foo and its sub packages,
+// anything else is matched with path.Match, eg "github.com/foo/*" or "main"
+func matchPackage(pattern string, pkg string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "/..."); ok {
+		return pkg == prefix || strings.HasPrefix(pkg, prefix+"/")
+	}
+	ok, err := path.Match(pattern, pkg)
+	return ok && err == nil
+}
+
+func matchAny(patterns []string, pkg string) bool {
+	for _, p := range patterns {
+		if matchPackage(p, pkg) {
+			return true
+		}
+	}
+	return false
+}
+
+// FuncPackage returns the package path of a function name as reported by runtime.Frame,
+// eg github.com/foo/bar for github.com/foo/bar.(*T).Method
+func FuncPackage(function string) string {
+	var slash = strings.LastIndex(function, "/")
+	if dot := strings.Index(function[slash+1:], "."); dot >= 0 {
+		return function[:slash+1+dot]
+	}
+	return function
+}
+
+// GetStackFrames captures the stack of the calling goroutine, skip is the number of frames
+// to skip above the caller of GetStackFrames (library frames are skipped regardless).
+func GetStackFrames(skip int, p StackParams) []Frame {
+	var maxDepth = p.MaxDepth
+	if maxDepth < 1 {
+		maxDepth = DefaultStackDepth
+	}
+
+	// runtime.Callers does not say if the stack was cut short, so grow until it fits
+	var pcs = make([]uintptr, 64)
+	for {
+		n := runtime.Callers(skip+2, pcs)
+		if n < len(pcs) {
+			pcs = pcs[:n]
+			break
+		}
+		pcs = make([]uintptr, len(pcs)*2)
+	}
+
+	var frames = []Frame{}
+	var iter = runtime.CallersFrames(pcs)
+	var toSkip = p.Skip
+
+	for {
+		f, more := iter.Next()
+		if f.Function != "" && !IsLibraryFrame(f.Function, f.File) && toSkip > 0 {
+			toSkip--
+		} else if f.Function != "" && !IsLibraryFrame(f.Function, f.File) {
+			var pkg = FuncPackage(f.Function)
+			if !matchAny(p.Exclude, pkg) && (len(p.Include) < 1 || matchAny(p.Include, pkg)) {
+				frames = append(frames, Frame{Function: f.Function, File: f.File, Line: f.Line, Package: pkg})
+				if len(frames) >= maxDepth {
+					break
+				}
+			}
+		}
+		if !more {
+			break
+		}
+	}
+
+	return frames
+}
+
+type stackFramer interface {
+	StackFrames() []Frame
+}
+
+// FramesFromArg returns the frames of a stack trace arg, which is a shared.StackTrace when logging,
+// and a map after a @bunion record has been decoded.
+func FramesFromArg(v interface{}) ([]Frame, bool) {
+	switch x := v.(type) {
+	case stackFramer:
+		frames := x.StackFrames()
+		return frames, len(frames) > 0
+	case map[string]interface{}:
+		list, ok := x["Frames"].([]interface{})
+		if !ok || len(list) < 1 {
+			return nil, false
+		}
+		var frames = make([]Frame, 0, len(list))
+		for _, f := range list {
+			m, ok := f.(map[string]interface{})
+			if !ok {
+				return nil, false
+			}
+			function, _ := m["function"].(string)
+			file, _ := m["file"].(string)
+			line, _ := m["line"].(float64)
+			pkg, _ := m["package"].(string)
+			frames = append(frames, Frame{Function: function, File: file, Line: int(line), Package: pkg})
+		}
+		return frames, true
+	}
+	return nil, false
+}
+
+// PrettyFrames renders frames one per line, dimmed, for terminal output.
+func PrettyFrames(frames []Frame) string {
+	var b strings.Builder
+	for _, f := range frames {
+		b.WriteString("\n    ")
+		b.WriteString(au.Col.Faint("at " + f.Function + " (" + f.File + ":" + strconv.Itoa(f.Line) + ")").String())
+	}
+	return b.String()
+}
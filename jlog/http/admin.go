@@ -0,0 +1,302 @@
+package jhttp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	ll "github.com/oresoftware/json-logging/jlog/level"
+	"github.com/oresoftware/json-logging/jlog/lib"
+	"github.com/oresoftware/json-logging/jlog/mult"
+	"github.com/oresoftware/json-logging/jlog/shared"
+)
+
+type AdminParams struct {
+	Loggers func() []shared.Logger // the loggers which are listed and can be changed, shared.RegisteredLoggers if nil (see shared.RegisterOwner)
+	Logger  shared.Logger          // records every change at WARN, lib.DefaultLogger if nil
+}
+
+// AdminChange is the body of a PUT or POST, it changes the level of the loggers with the
+// AppName App (and the FileLevel at index Output of a MultiLogger, or all of them if nil),
+// or, if Name is set, the level of a name and the names below it (see lib.Named).
+type AdminChange struct {
+	App     string `json:"app,omitempty"`
+	Output  *int   `json:"output,omitempty"`
+	Name    string `json:"name,omitempty"`
+	Level   string `json:"level"`
+	Expires string `json:"expires,omitempty"` // eg 10m, the previous level is restored after this long
+}
+
+// AdminState is the response of every request, the loggers are listed in registration order.
+type AdminState struct {
+	Loggers     []AdminLogger     `json:"loggers"`
+	NamedLevels map[string]string `json:"named_levels"`
+	Levels      []string          `json:"levels"` // every registered level, least severe first
+	Restores    []AdminRestore    `json:"restores"`
+}
+
+type AdminLogger struct {
+	Id int `json:"id"`
+	shared.LoggerInfo
+}
+
+// AdminRestore is a pending restore of a change with an expiry.
+type AdminRestore struct {
+	App    string    `json:"app,omitempty"`
+	Output *int      `json:"output,omitempty"`
+	Name   string    `json:"name,omitempty"`
+	Level  string    `json:"level"` // the level which is restored, empty if a name level is removed
+	At     time.Time `json:"at"`
+}
+
+// Admin is an http.Handler for an admin port: GET lists the loggers, their levels and outputs,
+// PUT/POST apply an AdminChange.
+type Admin struct {
+	p        AdminParams
+	mtx      sync.Mutex
+	restores map[target]*restore
+}
+
+// target is something with a level, a logger (and an output of a MultiLogger) or a name
+type target struct {
+	l      shared.Logger
+	output int
+	name   string
+}
+
+type restore struct {
+	level    ll.LogLevel
+	hasLevel bool // false if the name had no level of its own before the change
+	at       time.Time
+	timer    *time.Timer
+}
+
+func NewAdmin(p AdminParams) *Admin {
+	if p.Loggers == nil {
+		p.Loggers = shared.RegisteredLoggers
+	}
+	if p.Logger == nil {
+		p.Logger = lib.DefaultLogger
+	}
+	return &Admin{p: p, restores: map[target]*restore{}}
+}
+
+func (a *Admin) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+	case http.MethodPut, http.MethodPost:
+		var c AdminChange
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&c); err != nil {
+			http.Error(w, "invalid body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if status, err := a.Apply(c); err != nil {
+			http.Error(w, err.Error(), status)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, HEAD, PUT, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	var enc = json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(a.State()); err != nil {
+		a.p.Logger.Error("json-logging: could not write the admin response:", err)
+	}
+}
+
+// State lists the loggers, the levels set by name and the pending restores.
+func (a *Admin) State() AdminState {
+	var state = AdminState{
+		Loggers:     []AdminLogger{},
+		NamedLevels: map[string]string{},
+		Levels:      []string{},
+		Restores:    []AdminRestore{},
+	}
+	for i, l := range a.p.Loggers() {
+		if d, ok := l.(shared.Describer); ok {
+			state.Loggers = append(state.Loggers, AdminLogger{Id: i, LoggerInfo: d.Describe()})
+		}
+	}
+	for name, level := range shared.NamedLevels() {
+		state.NamedLevels[name] = level.String()
+	}
+	for _, info := range ll.Levels() {
+		state.Levels = append(state.Levels, info.Name)
+	}
+
+	a.mtx.Lock()
+	for t, r := range a.restores {
+		var x = AdminRestore{Name: t.name, At: r.at}
+		if d, ok := t.l.(shared.Describer); ok {
+			x.App = d.Describe().App
+			if _, ok := t.l.(*mult.MultiLogger); ok {
+				var output = t.output
+				x.Output = &output
+			}
+		}
+		if r.hasLevel {
+			x.Level = r.level.String()
+		}
+		state.Restores = append(state.Restores, x)
+	}
+	a.mtx.Unlock()
+
+	sort.Slice(state.Restores, func(i, j int) bool {
+		return state.Restores[i].At.Before(state.Restores[j].At)
+	})
+	return state
+}
+
+// Apply makes a change, the status is the http status for the error.
+func (a *Admin) Apply(c AdminChange) (int, error) {
+	level, ok := ll.Parse(c.Level)
+	if !ok {
+		return http.StatusBadRequest, fmt.Errorf("unknown level: %q", c.Level)
+	}
+
+	var expires time.Duration
+	if c.Expires != "" {
+		var err error
+		if expires, err = time.ParseDuration(c.Expires); err != nil || expires <= 0 {
+			return http.StatusBadRequest, fmt.Errorf("invalid expires: %q", c.Expires)
+		}
+	}
+
+	targets, status, err := a.targets(c)
+	if err != nil {
+		return status, err
+	}
+
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	for _, t := range targets {
+		// a change replaces a pending restore, but keeps the level from before the first change
+		r, ok := a.restores[t]
+		if ok {
+			r.timer.Stop()
+			delete(a.restores, t)
+		} else {
+			r = &restore{}
+			r.level, r.hasLevel = t.get()
+		}
+
+		t.set(level, true)
+
+		if expires > 0 {
+			var t = t
+			r.at = time.Now().Add(expires)
+			r.timer = time.AfterFunc(expires, func() {
+				a.restore(t, r)
+			})
+			a.restores[t] = r
+		}
+	}
+
+	var m = shared.MF{"level": level.String()}
+	for k, v := range map[string]string{"app": c.App, "name": c.Name, "expires": c.Expires} {
+		if v != "" {
+			m[k] = v
+		}
+	}
+	if c.Output != nil {
+		m["output"] = *c.Output
+	}
+	a.p.Logger.Warn("json-logging: log level changed", shared.NewMetaFields(&m))
+	return http.StatusOK, nil
+}
+
+func (a *Admin) restore(t target, r *restore) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	// the restore was replaced by a later change
+	if a.restores[t] != r {
+		return
+	}
+	delete(a.restores, t)
+	t.set(r.level, r.hasLevel)
+}
+
+func (a *Admin) targets(c AdminChange) ([]target, int, error) {
+	if c.Name != "" {
+		if c.App != "" || c.Output != nil {
+			return nil, http.StatusBadRequest, fmt.Errorf("name cannot be used with app or output")
+		}
+		return []target{{name: c.Name}}, http.StatusOK, nil
+	}
+	if c.App == "" {
+		return nil, http.StatusBadRequest, fmt.Errorf("app or name is required")
+	}
+
+	var results []target
+	for _, l := range a.p.Loggers() {
+		switch z := l.(type) {
+		case *lib.Logger:
+			if z.Describe().App != c.App {
+				continue
+			}
+			if c.Output != nil && *c.Output != 0 {
+				return nil, http.StatusBadRequest, fmt.Errorf("%s has a single output", c.App)
+			}
+			results = append(results, target{l: z})
+		case *mult.MultiLogger:
+			var info = z.Describe()
+			if info.App != c.App {
+				continue
+			}
+			if c.Output != nil {
+				if *c.Output < 0 || *c.Output >= len(info.Outputs) {
+					return nil, http.StatusBadRequest, fmt.Errorf("%s has no output %d", c.App, *c.Output)
+				}
+				results = append(results, target{l: z, output: *c.Output})
+				continue
+			}
+			for i := range info.Outputs {
+				results = append(results, target{l: z, output: i})
+			}
+		}
+	}
+
+	if len(results) < 1 {
+		return nil, http.StatusNotFound, fmt.Errorf("no logger with the app name %q", c.App)
+	}
+	return results, http.StatusOK, nil
+}
+
+// get returns the current level, ok is false for a name without a level of its own
+func (t target) get() (ll.LogLevel, bool) {
+	switch l := t.l.(type) {
+	case *lib.Logger:
+		return l.Level(), true
+	case *mult.MultiLogger:
+		return l.FileLevelAt(t.output)
+	}
+	level, ok := shared.NamedLevels()[t.name]
+	return level, ok
+}
+
+// set changes the level, or removes the level of a name if ok is false
+func (t target) set(level ll.LogLevel, ok bool) {
+	if !ok && t.l != nil {
+		return
+	}
+	switch l := t.l.(type) {
+	case *lib.Logger:
+		l.SetLogLevel(level)
+	case *mult.MultiLogger:
+		l.SetFileLevel(t.output, level)
+	default:
+		if ok {
+			shared.SetNamedLevel(t.name, level)
+		} else {
+			shared.ClearNamedLevel(t.name)
+		}
+	}
+}
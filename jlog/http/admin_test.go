@@ -0,0 +1,93 @@
+package jhttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	ll "github.com/oresoftware/json-logging/jlog/level"
+	"github.com/oresoftware/json-logging/jlog/lib"
+	"github.com/oresoftware/json-logging/jlog/mult"
+	"github.com/oresoftware/json-logging/jlog/shared"
+)
+
+func adminRequest(t *testing.T, h http.Handler, method string, body string) (int, AdminState) {
+	t.Helper()
+	var w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(method, "/debug/log", strings.NewReader(body)))
+	var state AdminState
+	if w.Code == http.StatusOK {
+		if err := json.Unmarshal(w.Body.Bytes(), &state); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return w.Code, state
+}
+
+func TestAdminChangesLevels(t *testing.T) {
+	var audit bytes.Buffer
+	single := lib.CreateLogger("admin-lib").SetOutput(io.Discard).SetLogLevel(ll.INFO)
+	multi := mult.New("admin-mult", "", []*mult.FileLevel{
+		{Level: ll.INFO, Writer: io.Discard},
+		{Level: ll.ERROR, Writer: io.Discard},
+	})
+
+	var admin = NewAdmin(AdminParams{
+		Loggers: func() []shared.Logger { return []shared.Logger{single, multi} },
+		Logger:  lib.CreateLogger("admin-audit").SetOutput(&audit).SetToJSONOutput(),
+	})
+
+	code, state := adminRequest(t, admin, http.MethodGet, "")
+	if code != http.StatusOK || len(state.Loggers) != 2 || state.Loggers[1].Outputs[1].Level != "ERROR" {
+		t.Fatalf("unexpected state: %d %#v", code, state)
+	}
+
+	code, state = adminRequest(t, admin, http.MethodPut, `{"app": "admin-lib", "level": "debug", "expires": "50ms"}`)
+	if code != http.StatusOK || state.Loggers[0].Level != "DEBUG" || len(state.Restores) != 1 || state.Restores[0].Level != "INFO" {
+		t.Fatalf("unexpected state: %d %#v", code, state)
+	}
+	if !single.IsDebugEnabled() {
+		t.Fatal("expected DEBUG to be enabled")
+	}
+
+	code, _ = adminRequest(t, admin, http.MethodPost, `{"app": "admin-mult", "output": 1, "level": "WARN"}`)
+	if code != http.StatusOK || multi.Files[1].Level != ll.WARN || multi.Files[0].Level != ll.INFO {
+		t.Fatalf("unexpected file levels: %d %v %v", code, multi.Files[0].Level, multi.Files[1].Level)
+	}
+
+	code, _ = adminRequest(t, admin, http.MethodPut, `{"name": "admin-db", "level": "TRACE", "expires": "50ms"}`)
+	if level, ok := shared.NamedLevel("admin-db.pool"); code != http.StatusOK || !ok || level != ll.TRACE {
+		t.Fatalf("unexpected named level: %d %v %v", code, level, ok)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if single.EffectiveLevel() != ll.INFO {
+		t.Fatalf("the level was not restored: %v", single.EffectiveLevel())
+	}
+	if _, ok := shared.NamedLevel("admin-db"); ok {
+		t.Fatal("the named level was not removed")
+	}
+	if n := strings.Count(audit.String(), "log level changed"); n != 3 {
+		t.Fatalf("expected three audit records, got %d", n)
+	}
+
+	for body, status := range map[string]int{
+		`{"app": "admin-lib", "level": "LOUD"}`:                 http.StatusBadRequest,
+		`{"app": "admin-lib", "level": "INFO", "expires": "x"}`: http.StatusBadRequest,
+		`{"app": "admin-mult", "output": 2, "level": "INFO"}`:   http.StatusBadRequest,
+		`{"app": "nope", "level": "INFO"}`:                      http.StatusNotFound,
+		`{"level": "INFO"}`:                                     http.StatusBadRequest,
+	} {
+		if code, _ := adminRequest(t, admin, http.MethodPut, body); code != status {
+			t.Fatalf("expected %d for %s, got %d", status, body, code)
+		}
+	}
+	if code, _ := adminRequest(t, admin, http.MethodDelete, ""); code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405, got %d", code)
+	}
+}
@@ -0,0 +1,195 @@
+package jhttp
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	uuid "github.com/google/uuid"
+	jctx "github.com/oresoftware/json-logging/jlog/ctx"
+	ll "github.com/oresoftware/json-logging/jlog/level"
+	"github.com/oresoftware/json-logging/jlog/lib"
+	"github.com/oresoftware/json-logging/jlog/shared"
+)
+
+const RequestIdHeader = "X-Request-Id"
+
+type MiddlewareParams struct {
+	Logger          shared.Logger                // the parent of the per request loggers, lib.DefaultLogger if nil
+	RequestIdHeader string                       // the incoming/outgoing request id header, X-Request-Id if empty
+	NewRequestId    func() string                // generates request ids when the request has none, a uuid by default
+	Headers         []string                     // request headers copied into the access record, under "headers"
+	ResponseHeaders []string                     // response headers copied into the access record, under "response_headers"
+	Level           func(status int) ll.LogLevel // the level of the access record, see DefaultLevel
+	Message         string                       // the message of the access record, "http request" if empty
+}
+
+// DefaultLevel logs 5xx at ERROR, 4xx at WARN and everything else at INFO.
+func DefaultLevel(status int) ll.LogLevel {
+	switch {
+	case status >= 500:
+		return ll.ERROR
+	case status >= 400:
+		return ll.WARN
+	}
+	return ll.INFO
+}
+
+// NewMiddleware gives every request a Child logger with a "request_id" meta field, stashed in the
+// request context (see lib.FromContext and jctx.LoggerFrom), and logs an access record when the handler returns,
+// or with status 500 when it panics.
+func NewMiddleware(p MiddlewareParams) func(http.Handler) http.Handler {
+	if p.Logger == nil {
+		p.Logger = lib.DefaultLogger
+	}
+	if p.RequestIdHeader == "" {
+		p.RequestIdHeader = RequestIdHeader
+	}
+	if p.NewRequestId == nil {
+		p.NewRequestId = func() string {
+			return uuid.New().String()
+		}
+	}
+	if p.Level == nil {
+		p.Level = DefaultLevel
+	}
+	if p.Message == "" {
+		p.Message = "http request"
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var start = time.Now()
+
+			var id = strings.TrimSpace(r.Header.Get(p.RequestIdHeader))
+			if id == "" || len(id) > 200 {
+				id = p.NewRequestId()
+			}
+			w.Header().Set(p.RequestIdHeader, id)
+
+			// a logger which cannot make children gets the request_id from the context (see the Ctx methods),
+			// and on the request record below
+			var log = p.Logger
+			c, isChild := log.(shared.ChildMaker)
+			if isChild {
+				log = c.ChildLogger(&map[string]interface{}{"request_id": id})
+			}
+
+			var ctx = jctx.WithRequestId(r.Context(), id)
+			ctx = jctx.WithLogger(ctx, log)
+
+			var sw = &statusWriter{ResponseWriter: w}
+
+			// deferred, so that a panicking handler gets a record too, the panic carries on afterwards
+			defer func() {
+				var rec = recover()
+
+				var status = sw.status
+				if rec != nil {
+					status = http.StatusInternalServerError
+				} else if status == 0 {
+					status = http.StatusOK
+				}
+
+				var m = shared.MF{
+					"method":      r.Method,
+					"path":        r.URL.Path,
+					"status":      status,
+					"bytes":       sw.bytes,
+					"duration_ms": float64(time.Since(start).Microseconds()) / 1000,
+					"remote_addr": r.RemoteAddr,
+				}
+				if !isChild {
+					m["request_id"] = id
+				}
+				if rec != nil {
+					m["panic"] = true
+				}
+				if h := captureHeaders(r.Header, p.Headers); h != nil {
+					m["headers"] = h
+				}
+				if h := captureHeaders(w.Header(), p.ResponseHeaders); h != nil {
+					m["response_headers"] = h
+				}
+
+				// the stack trace would only show the middleware
+				log.Log(p.Level(status), p.Message, shared.NewMetaFields(&m), shared.Opts{IsSkipStackTrace: true})
+
+				if rec != nil {
+					panic(rec)
+				}
+			}()
+
+			next.ServeHTTP(sw, r.WithContext(ctx))
+		})
+	}
+}
+
+// Middleware is NewMiddleware with the defaults and the given logger.
+func Middleware(l shared.Logger) func(http.Handler) http.Handler {
+	return NewMiddleware(MiddlewareParams{Logger: l})
+}
+
+func captureHeaders(h http.Header, names []string) map[string]string {
+	var results map[string]string
+	for _, name := range names {
+		if v := h.Values(name); len(v) > 0 {
+			if results == nil {
+				results = map[string]string{}
+			}
+			results[http.CanonicalHeaderKey(name)] = strings.Join(v, ", ")
+		}
+	}
+	return results
+}
+
+// statusWriter records the status and the number of bytes written
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	// 1xx responses other than 101 are followed by the final status
+	if w.status == 0 && (status >= 200 || status == http.StatusSwitchingProtocols) {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		if w.status == 0 {
+			w.status = http.StatusOK
+		}
+		f.Flush()
+	}
+}
+
+func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		if w.status == 0 {
+			w.status = http.StatusSwitchingProtocols
+		}
+		return h.Hijack()
+	}
+	return nil, nil, errors.New("json-logging: the response writer does not support hijacking")
+}
@@ -0,0 +1,165 @@
+package jhttp
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/oresoftware/json-logging/jlog/bunion"
+	jctx "github.com/oresoftware/json-logging/jlog/ctx"
+	ll "github.com/oresoftware/json-logging/jlog/level"
+	"github.com/oresoftware/json-logging/jlog/lib"
+	"github.com/oresoftware/json-logging/jlog/shared"
+)
+
+func decodeRecords(t *testing.T, b []byte) []*bunion.Record {
+	t.Helper()
+	var records []*bunion.Record
+	var d = bunion.NewDecoder(bytes.NewReader(b))
+	for {
+		rec, err := d.Decode()
+		if err != nil {
+			return records
+		}
+		records = append(records, rec)
+	}
+}
+
+func TestMiddlewareLogsAccessRecords(t *testing.T) {
+	var buf bytes.Buffer
+	log := lib.CreateLogger("http-json").SetOutput(&buf).SetToJSONOutput().SetLogLevel(ll.TRACE)
+
+	var handler = NewMiddleware(MiddlewareParams{
+		Logger:          log,
+		Headers:         []string{"user-agent"},
+		ResponseHeaders: []string{"Content-Type"},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if jctx.RequestId(r.Context()) == "" {
+			t.Error("expected a request id in the context")
+		}
+		lib.FromContext(r.Context()).Info("in the handler")
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("hello"))
+	}))
+
+	req := httptest.NewRequest("GET", "/hello?x=1", nil)
+	req.Header.Set("User-Agent", "test-agent")
+	req.Header.Set("X-Request-Id", "abc")
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, req)
+
+	if res.Header().Get("X-Request-Id") != "abc" {
+		t.Fatalf("expected the incoming request id to be echoed, got %q", res.Header().Get("X-Request-Id"))
+	}
+
+	res = httptest.NewRecorder()
+	handler.ServeHTTP(res, httptest.NewRequest("POST", "/missing", nil))
+	generated := res.Header().Get("X-Request-Id")
+	if generated == "" {
+		t.Fatal("expected a generated request id")
+	}
+
+	records := decodeRecords(t, buf.Bytes())
+	if len(records) != 4 {
+		t.Fatalf("expected four records, got %d", len(records))
+	}
+
+	if records[0].Meta["request_id"] != "abc" || records[2].Meta["request_id"] != generated {
+		t.Fatalf("expected the handler records to carry the request id: %#v %#v", records[0].Meta, records[2].Meta)
+	}
+
+	access := records[1]
+	if access.Level != "INFO" || access.Meta["request_id"] != "abc" || access.Meta["method"] != "GET" ||
+		access.Meta["path"] != "/hello" || access.Meta["status"] != float64(200) || access.Meta["bytes"] != float64(5) {
+		t.Fatalf("unexpected access record: %s", access.Raw)
+	}
+	if _, ok := access.Meta["duration_ms"].(float64); !ok || !strings.HasPrefix(access.Meta["remote_addr"].(string), "192.0.2.1") {
+		t.Fatalf("unexpected access record: %s", access.Raw)
+	}
+	if h := access.Meta["headers"].(map[string]interface{}); h["User-Agent"] != "test-agent" {
+		t.Fatalf("expected the user agent to be captured: %#v", h)
+	}
+	if h := access.Meta["response_headers"].(map[string]interface{}); h["Content-Type"] != "text/plain" {
+		t.Fatalf("expected the content type to be captured: %#v", h)
+	}
+
+	if records[3].Level != "WARN" || records[3].Meta["status"] != float64(404) {
+		t.Fatalf("expected a 404 at WARN: %s", records[3].Raw)
+	}
+}
+
+func TestMiddlewareLogsPanickingHandlers(t *testing.T) {
+	var buf bytes.Buffer
+	log := lib.CreateLogger("http-panic").SetOutput(&buf).SetToJSONOutput().SetLogLevel(ll.TRACE)
+
+	var handler = Middleware(log)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		panic("boom")
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/fail", nil))
+
+	rePanicked := func() (r interface{}) {
+		defer func() { r = recover() }()
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/panic", nil))
+		return nil
+	}()
+	if rePanicked != "boom" {
+		t.Fatalf("expected the panic to carry on, got %#v", rePanicked)
+	}
+
+	records := decodeRecords(t, buf.Bytes())
+	if len(records) != 2 {
+		t.Fatalf("expected two records, got %d", len(records))
+	}
+	for _, r := range records {
+		// the message only, no stack trace arg
+		if r.Level != "ERROR" || len(r.Args) != 1 {
+			t.Fatalf("expected an ERROR record without a stack trace: %s", r.Raw)
+		}
+	}
+	if records[1].Meta["status"] != float64(500) || records[1].Meta["panic"] != true {
+		t.Fatalf("expected the panic to be logged as a 500: %s", records[1].Raw)
+	}
+}
+
+// coreLogger only has the methods of shared.Logger, like a logger from another package would
+type coreLogger struct {
+	shared.Logger
+}
+
+func TestMiddlewareTakesAnyLogger(t *testing.T) {
+	var buf bytes.Buffer
+	var log = coreLogger{lib.CreateLogger("http-core").SetOutput(&buf).SetToJSONOutput()}
+
+	var handler = Middleware(log)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if jctx.LoggerFrom(r.Context()) != log {
+			t.Error("expected the logger itself in the context")
+		}
+	}))
+	req := httptest.NewRequest("GET", "/hello", nil)
+	req.Header.Set("X-Request-Id", "abc")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	records := decodeRecords(t, buf.Bytes())
+	if len(records) != 1 || records[0].Meta["request_id"] != "abc" {
+		t.Fatalf("expected the request id on the record: %q", buf.String())
+	}
+
+	// the optional interfaces are skipped
+	shared.RegisterLogger(log)
+	defer shared.UnregisterLogger(log)
+	if err := shared.FlushAll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,202 @@
+package jhttp
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	jctx "github.com/oresoftware/json-logging/jlog/ctx"
+	ll "github.com/oresoftware/json-logging/jlog/level"
+	"github.com/oresoftware/json-logging/jlog/lib"
+	"github.com/oresoftware/json-logging/jlog/shared"
+)
+
+const Redacted = "REDACTED"
+
+// query params which are redacted when TransportParams.RedactQuery is nil
+var DefaultRedactQuery = []string{
+	"access_token", "api_key", "apikey", "code", "key", "password", "secret", "signature", "token",
+}
+
+type TransportParams struct {
+	Logger          shared.Logger                // used when the request context has no logger, lib.DefaultLogger if nil
+	Base            http.RoundTripper            // the transport which sends the requests, http.DefaultTransport if nil
+	RequestIdHeader string                       // the outgoing request id header, X-Request-Id if empty
+	RedactQuery     []string                     // query params with redacted values in the record, "*" redacts all of them
+	MaxRetries      int                          // retries of idempotent requests after a network error or a 502, 503 or 504
+	RetryBackoff    time.Duration                // the wait before the first retry, doubled for each one after, 100ms if 0
+	Level           func(status int) ll.LogLevel // the level of the record, see DefaultLevel, errors are always logged at ERROR
+	Message         string                       // the message of the record, "http client request" if empty
+}
+
+// Transport is an http.RoundTripper which logs every outbound request, and passes the request id
+// of the caller's context (see jctx.WithRequestId and NewMiddleware) on in a header.
+type Transport struct {
+	p TransportParams
+}
+
+func NewTransport(p TransportParams) *Transport {
+	if p.Logger == nil {
+		p.Logger = lib.DefaultLogger
+	}
+	if p.Base == nil {
+		p.Base = http.DefaultTransport
+	}
+	if p.RequestIdHeader == "" {
+		p.RequestIdHeader = RequestIdHeader
+	}
+	if p.RedactQuery == nil {
+		p.RedactQuery = DefaultRedactQuery
+	}
+	if p.RetryBackoff <= 0 {
+		p.RetryBackoff = 100 * time.Millisecond
+	}
+	if p.Level == nil {
+		p.Level = DefaultLevel
+	}
+	if p.Message == "" {
+		p.Message = "http client request"
+	}
+	return &Transport{p: p}
+}
+
+// NewClient is an http.Client with a Transport wrapping http.DefaultTransport.
+func NewClient(l shared.Logger) *http.Client {
+	return &http.Client{Transport: NewTransport(TransportParams{Logger: l})}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var start = time.Now()
+	var ctx = req.Context()
+
+	var log = jctx.LoggerFrom(ctx)
+	if log == nil {
+		log = t.p.Logger
+	}
+
+	var m = shared.MF{
+		"method": req.Method,
+		"url":    RedactURL(req.URL, t.p.RedactQuery),
+	}
+
+	// a RoundTripper must not modify the request, so the header goes on a clone
+	if id := jctx.RequestId(ctx); id != "" {
+		m["request_id"] = id
+		if req.Header.Get(t.p.RequestIdHeader) == "" {
+			req = req.Clone(ctx)
+			req.Header.Set(t.p.RequestIdHeader, id)
+		}
+	}
+
+	var res *http.Response
+	var err error
+	var retries = 0
+
+	for {
+		res, err = t.p.Base.RoundTrip(req)
+		if retries >= t.p.MaxRetries || !shouldRetry(req, res, err) {
+			break
+		}
+
+		if req.GetBody != nil {
+			body, berr := req.GetBody()
+			if berr != nil {
+				break
+			}
+			req = req.Clone(ctx)
+			req.Body = body
+		}
+		if res != nil {
+			res.Body.Close()
+		}
+
+		var wait = t.p.RetryBackoff << retries
+
+		var timer = time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			res, err = nil, ctx.Err()
+		case <-timer.C:
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		retries++
+	}
+
+	m["duration_ms"] = float64(time.Since(start).Microseconds()) / 1000
+	m["retries"] = retries
+
+	// the stack trace would only show the transport
+	if err != nil {
+		log.Log(ll.ERROR, t.p.Message, shared.NewMetaFields(&m), err, shared.Opts{IsSkipStackTrace: true})
+		return nil, err
+	}
+
+	m["status"] = res.StatusCode
+	log.Log(t.p.Level(res.StatusCode), t.p.Message, shared.NewMetaFields(&m), shared.Opts{IsSkipStackTrace: true})
+	return res, nil
+}
+
+func shouldRetry(req *http.Request, res *http.Response, err error) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	if !isIdempotent(req) {
+		return false
+	}
+	if err != nil {
+		return req.Context().Err() == nil
+	}
+	switch res.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != ""
+}
+
+// RedactURL returns the url without a password, and with the values of the given query params
+// replaced by REDACTED ("*" redacts every value).
+func RedactURL(u *url.URL, params []string) string {
+	if u == nil {
+		return ""
+	}
+	var z = *u
+	if z.RawQuery != "" && len(params) > 0 {
+		var q = z.Query()
+		var isChanged = false
+		for k, vals := range q {
+			if !matchParam(params, k) {
+				continue
+			}
+			for i := range vals {
+				vals[i] = Redacted
+			}
+			isChanged = true
+		}
+		// Encode sorts the params, so only re-encode when something was redacted
+		if isChanged {
+			z.RawQuery = q.Encode()
+		}
+	}
+	return z.Redacted()
+}
+
+func matchParam(params []string, name string) bool {
+	for _, p := range params {
+		if p == "*" || strings.EqualFold(p, name) {
+			return true
+		}
+	}
+	return false
+}
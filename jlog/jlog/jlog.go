@@ -4,7 +4,9 @@ import (
   logger "github.com/oresoftware/json-logging/jlog/lib"
   "os"
   "strings"
+  "time"
   ll "github.com/oresoftware/json-logging/jlog/level"
+  "github.com/oresoftware/json-logging/jlog/shared"
 )
 
 var appName = func() string {
@@ -28,3 +30,9 @@ var envPrefix = func() string {
 }()
 
 var Stdout = logger.CreateLogger(appName).SetEnvPrefix("").SetLogLevel(ll.TRACE)
+
+// Shutdown flushes every registered logger (lib and mult) within the timeout and then closes their outputs.
+// Call it before os.Exit, so that async/buffered records are not lost.
+func Shutdown(timeout time.Duration) error {
+  return shared.Shutdown(timeout)
+}
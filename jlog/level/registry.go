@@ -0,0 +1,217 @@
+package ll
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/oresoftware/json-logging/jlog/au"
+)
+
+// Style renders a level name for pretty output, eg with the colors of jlog/au.
+type Style func(name string) string
+
+// LevelInfo describes a level, the builtin ones are registered from the start.
+type LevelInfo struct {
+	Level    LogLevel // the value, set by Register for new levels
+	Severity int      // orders the levels, records at or above a logger's level are written, see LogLevel.Severity
+	Name     string   // the name in pretty output, env vars and filters, eg NOTICE
+	JSONName string   // the level field of @bunion:v1 records, Name if empty
+	Style    Style    // how pretty output renders the name, the plain name if nil
+}
+
+var registry = struct {
+	mtx    sync.RWMutex
+	levels map[LogLevel]LevelInfo
+	names  map[string]LogLevel
+	next   LogLevel
+}{
+	// custom levels get values of their own, away from CRITICAL+1 and such
+	next:   100,
+	levels: map[LogLevel]LevelInfo{},
+	names:  map[string]LogLevel{},
+}
+
+func init() {
+	for _, info := range []LevelInfo{
+		{Level: TRACE, Severity: 10, Name: "TRACE", Style: func(s string) string { return au.Col.Gray(4, s).String() }},
+		{Level: DEBUG, Severity: 20, Name: "DEBUG", Style: func(s string) string { return au.Col.Bold(s).String() }},
+		{Level: INFO, Severity: 30, Name: "INFO", Style: func(s string) string { return au.Col.Gray(12, s).String() }},
+		{Level: WARN, Severity: 40, Name: "WARN", Style: func(s string) string { return au.Col.Magenta(s).String() }},
+		{Level: ERROR, Severity: 50, Name: "ERROR", Style: func(s string) string { return au.Col.Underline(au.Col.Bold(au.Col.Red(s))).String() }},
+		{Level: CRITICAL, Severity: 60, Name: "CRITICAL", Style: func(s string) string { return au.Col.Bold(au.Col.BgRed(s)).String() }},
+	} {
+		// not via Register, which gives new levels values of their own
+		info.JSONName = info.Name
+		registry.levels[info.Level] = info
+		registry.names[info.Name] = info.Level
+	}
+}
+
+// Register adds a level and returns its value, eg NOTICE between INFO (30) and WARN (40):
+//
+//	var NOTICE = ll.MustRegister(ll.LevelInfo{Severity: 35, Name: "NOTICE", Style: ...})
+//
+// The builtin levels keep their values (TRACE is 0 ... CRITICAL is 5), new levels get values of their own,
+// and their severity says where they go. Registering a name again replaces it, eg to change the style
+// of a builtin level, its value and severity stay the same.
+func Register(info LevelInfo) (LogLevel, error) {
+	info.Name = strings.ToUpper(strings.TrimSpace(info.Name))
+	if info.Name == "" {
+		return 0, fmt.Errorf("json-logging: a level needs a name")
+	}
+	if info.JSONName == "" {
+		info.JSONName = info.Name
+	}
+
+	registry.mtx.Lock()
+	defer registry.mtx.Unlock()
+
+	var level, exists = registry.names[info.Name]
+	if exists {
+		var existing = registry.levels[level]
+		if existing.Name != info.Name {
+			return 0, fmt.Errorf("json-logging: the name %s is already used by %s", info.Name, existing.Name)
+		}
+		if info.Severity == 0 {
+			info.Severity = existing.Severity
+		}
+		if info.Severity != existing.Severity {
+			return 0, fmt.Errorf("json-logging: the severity of %s is %d, it cannot change", info.Name, existing.Severity)
+		}
+	} else {
+		level = registry.next
+		if info.Severity < 1 {
+			return 0, fmt.Errorf("json-logging: level %s needs a positive severity", info.Name)
+		}
+	}
+	info.Level = level
+
+	for _, other := range registry.levels {
+		if other.Level != level && other.Severity == info.Severity {
+			return 0, fmt.Errorf("json-logging: the severity %d is already used by %s", info.Severity, other.Name)
+		}
+	}
+	if other, ok := registry.names[strings.ToUpper(info.JSONName)]; ok && other != level {
+		return 0, fmt.Errorf("json-logging: the name %s is already used by %s", info.JSONName, registry.levels[other].Name)
+	}
+
+	if exists {
+		delete(registry.names, strings.ToUpper(registry.levels[level].JSONName))
+	} else {
+		registry.next++
+	}
+	registry.levels[level] = info
+	registry.names[info.Name] = level
+	registry.names[strings.ToUpper(info.JSONName)] = level
+	return level, nil
+}
+
+// MustRegister is Register, but panics on errors, for use in init functions or package level vars.
+func MustRegister(info LevelInfo) LogLevel {
+	level, err := Register(info)
+	if err != nil {
+		panic(err)
+	}
+	return level
+}
+
+// Lookup returns the registered info of a level.
+func Lookup(level LogLevel) (LevelInfo, bool) {
+	registry.mtx.RLock()
+	defer registry.mtx.RUnlock()
+	info, ok := registry.levels[level]
+	return info, ok
+}
+
+// Parse returns the level with this name or JSON name, case insensitive.
+func Parse(name string) (LogLevel, bool) {
+	registry.mtx.RLock()
+	defer registry.mtx.RUnlock()
+	level, ok := registry.names[strings.ToUpper(strings.TrimSpace(name))]
+	return level, ok
+}
+
+// Levels returns every registered level, least severe first.
+func Levels() []LevelInfo {
+	registry.mtx.RLock()
+	var results = make([]LevelInfo, 0, len(registry.levels))
+	for _, info := range registry.levels {
+		results = append(results, info)
+	}
+	registry.mtx.RUnlock()
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Severity < results[j].Severity
+	})
+	return results
+}
+
+// Shift moves a level n registered levels, eg Shift(INFO, -1) is DEBUG, stopping at the least and most severe ones.
+// A level which is not registered moves from the closest registered level below it.
+func Shift(level LogLevel, n int) LogLevel {
+	if n == 0 {
+		return level
+	}
+	var levels = Levels()
+	var severity = level.Severity()
+	var i = sort.Search(len(levels), func(i int) bool {
+		return levels[i].Severity > severity
+	}) - 1
+	if i < 0 && n < 0 {
+		return level
+	}
+	i += n
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(levels) {
+		i = len(levels) - 1
+	}
+	return levels[i].Level
+}
+
+// Severity orders the levels, the builtin ones are 10 (TRACE) to 60 (CRITICAL), and levels which
+// are not registered continue that spacing, eg CRITICAL+1 is 70.
+func (l LogLevel) Severity() int {
+	if l < TRACE || l > CRITICAL {
+		if info, ok := Lookup(l); ok {
+			return info.Severity
+		}
+	}
+	return (int(l) + 1) * 10
+}
+
+// AtLeast is true if l is as severe as min or more, it is how loggers compare a record's level with their own.
+func (l LogLevel) AtLeast(min LogLevel) bool {
+	return l == min || l.Severity() >= min.Severity()
+}
+
+// String is the registered name, or LEVEL(n) for levels which are not registered.
+func (l LogLevel) String() string {
+	if info, ok := Lookup(l); ok {
+		return info.Name
+	}
+	return fmt.Sprintf("LEVEL(%d)", int(l))
+}
+
+// JSONName is the level field of @bunion:v1 records.
+func (l LogLevel) JSONName() string {
+	if info, ok := Lookup(l); ok {
+		return info.JSONName
+	}
+	return l.String()
+}
+
+// Pretty is the styled name for pretty output.
+func (l LogLevel) Pretty() string {
+	info, ok := Lookup(l)
+	if !ok {
+		return "<undefined>"
+	}
+	if info.Style == nil {
+		return info.Name
+	}
+	return info.Style(info.Name)
+}
@@ -0,0 +1,105 @@
+package ll
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuiltinLevelsKeepTheirValues(t *testing.T) {
+	var zero LogLevel
+	if zero != TRACE || zero.String() != "TRACE" || CRITICAL != 5 {
+		t.Fatalf("unexpected builtin levels: %d %s %d", zero, zero.String(), CRITICAL)
+	}
+	if Shift(zero, 1) != DEBUG || Shift(WARN, -1) != INFO {
+		t.Fatalf("unexpected shifts: %v %v", Shift(zero, 1), Shift(WARN, -1))
+	}
+}
+
+func TestRegisterAddsCustomLevels(t *testing.T) {
+	notice, err := Register(LevelInfo{Severity: 35, Name: "notice", JSONName: "note", Style: strings.ToLower})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if notice <= CRITICAL || notice.Severity() != 35 {
+		t.Fatalf("expected a value of its own: %d %d", notice, notice.Severity())
+	}
+	if notice.String() != "NOTICE" || notice.JSONName() != "note" || notice.Pretty() != "notice" {
+		t.Fatalf("unexpected names: %s %s %s", notice.String(), notice.JSONName(), notice.Pretty())
+	}
+	for _, name := range []string{"notice", "NOTICE", "Note"} {
+		if level, ok := Parse(name); !ok || level != notice {
+			t.Fatalf("could not parse %q: %v %v", name, level, ok)
+		}
+	}
+
+	if !notice.AtLeast(INFO) || notice.AtLeast(WARN) || !WARN.AtLeast(notice) || INFO.AtLeast(notice) {
+		t.Fatal("expected NOTICE to be between INFO and WARN")
+	}
+	if Shift(INFO, 1) != notice || Shift(notice, 1) != WARN {
+		t.Fatalf("unexpected shifts: %v %v", Shift(INFO, 1), Shift(notice, 1))
+	}
+
+	var prev int
+	for i, info := range Levels() {
+		if i > 0 && info.Severity <= prev {
+			t.Fatalf("levels are not sorted: %v", Levels())
+		}
+		prev = info.Severity
+	}
+
+	// registering the same name again replaces it, and keeps the value and severity
+	if level, err := Register(LevelInfo{Name: "NOTICE"}); err != nil || level != notice {
+		t.Fatal(level, err)
+	}
+	if _, ok := Parse("note"); ok {
+		t.Fatal("the old JSON name should be gone")
+	}
+	if notice.JSONName() != "NOTICE" || notice.Pretty() != "NOTICE" || notice.Severity() != 35 {
+		t.Fatalf("unexpected names after replacing: %s %s", notice.JSONName(), notice.Pretty())
+	}
+}
+
+func TestRegisterRejectsConflicts(t *testing.T) {
+	for _, info := range []LevelInfo{
+		{Severity: 31},
+		{Name: "CHATTY"},
+		{Severity: 30, Name: "CHATTY"},
+		{Severity: 32, Name: "warn"},
+		{Severity: 33, Name: "LOUD", JSONName: "error"},
+		{Name: "INFO", Severity: 45},
+	} {
+		if _, err := Register(info); err == nil {
+			t.Fatalf("expected an error registering %#v", info)
+		}
+	}
+
+	if s := LogLevel(7).String(); s != "LEVEL(7)" {
+		t.Fatalf("unexpected name of an unregistered level: %s", s)
+	}
+	if s := LogLevel(7).Pretty(); s != "<undefined>" {
+		t.Fatalf("unexpected pretty name of an unregistered level: %s", s)
+	}
+	if !(CRITICAL + 1).AtLeast(CRITICAL) {
+		t.Fatal("expected CRITICAL+1 to be above every builtin level")
+	}
+}
+
+func TestShift(t *testing.T) {
+	for _, c := range []struct {
+		level LogLevel
+		n     int
+		want  LogLevel
+	}{
+		{INFO, -1, DEBUG},
+		{WARN, 1, ERROR},
+		{TRACE, -3, TRACE},
+		{CRITICAL, 10, CRITICAL},
+		{-1, -1, -1},
+		{-1, 1, TRACE},
+	} {
+		if got := Shift(c.level, c.n); got != c.want {
+			t.Fatalf("Shift(%d, %d) = %d, want %d", c.level, c.n, got, c.want)
+		}
+	}
+}
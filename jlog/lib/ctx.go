@@ -0,0 +1,68 @@
+package lib
+
+import (
+	"context"
+
+	jctx "github.com/oresoftware/json-logging/jlog/ctx"
+	ll "github.com/oresoftware/json-logging/jlog/level"
+)
+
+// WithContext stashes this logger in the context, retrieve it with FromContext.
+func (l *Logger) WithContext(ctx context.Context) context.Context {
+	return jctx.WithLogger(ctx, l)
+}
+
+// FromContext returns the logger stashed via WithContext, or DefaultLogger.
+func FromContext(ctx context.Context) *Logger {
+	if z, ok := jctx.LoggerFrom(ctx).(*Logger); ok && z != nil {
+		return z
+	}
+	return DefaultLogger
+}
+
+// the context fields go first, so that meta fields passed explicitly take precedence
+func withCtxFields(ctx context.Context, args []interface{}) []interface{} {
+	return append([]interface{}{jctx.MetaFields(ctx)}, args...)
+}
+
+func (l *Logger) TraceCtx(ctx context.Context, args ...interface{}) {
+	if !l.IsLevelEnabled(ll.TRACE) {
+		return
+	}
+	l.Trace(withCtxFields(ctx, args)...)
+}
+
+func (l *Logger) DebugCtx(ctx context.Context, args ...interface{}) {
+	if !l.IsLevelEnabled(ll.DEBUG) {
+		return
+	}
+	l.Debug(withCtxFields(ctx, args)...)
+}
+
+func (l *Logger) InfoCtx(ctx context.Context, args ...interface{}) {
+	if !l.IsLevelEnabled(ll.INFO) {
+		return
+	}
+	l.Info(withCtxFields(ctx, args)...)
+}
+
+func (l *Logger) WarnCtx(ctx context.Context, args ...interface{}) {
+	if !l.IsLevelEnabled(ll.WARN) {
+		return
+	}
+	l.Warn(withCtxFields(ctx, args)...)
+}
+
+func (l *Logger) ErrorCtx(ctx context.Context, args ...interface{}) {
+	if !l.IsLevelEnabled(ll.ERROR) {
+		return
+	}
+	l.Error(withCtxFields(ctx, args)...)
+}
+
+func (l *Logger) CriticalCtx(ctx context.Context, args ...interface{}) {
+	if !l.IsLevelEnabled(ll.CRITICAL) {
+		return
+	}
+	l.Critical(withCtxFields(ctx, args)...)
+}
@@ -0,0 +1,64 @@
+package lib
+
+import (
+	"time"
+
+	"github.com/oresoftware/json-logging/jlog/dedup"
+	ll "github.com/oresoftware/json-logging/jlog/level"
+	"github.com/oresoftware/json-logging/jlog/shared"
+)
+
+// SetDedupWindow swallows records identical to the previous one (same level, meta and args) for this long
+// after it was written, then logs "previous message repeated N times over ...". 0 turns dedup off.
+func (l *Logger) SetDedupWindow(window time.Duration) *Logger {
+	var d *dedup.Deduper
+	if window > 0 {
+		d = dedup.NewDeduper(dedup.Params{Window: window}, l.logRepeat)
+	}
+
+	l.Mtx.Lock()
+	old := l.Deduper
+	l.Deduper = d
+	l.Mtx.Unlock()
+
+	if old != nil {
+		old.Flush()
+	}
+	return l
+}
+
+// isRepeat is true if dedup swallows this record
+func (l *Logger) isRepeat(level ll.LogLevel, m *MetaFields, args *[]interface{}) bool {
+	l.Mtx.RLock()
+	d := l.Deduper
+	l.Mtx.RUnlock()
+
+	if d == nil || m == nil || args == nil {
+		return false
+	}
+	return !d.Allow(level, *m.Map(), *args)
+}
+
+func (l *Logger) flushRepeats() {
+	l.Mtx.RLock()
+	d := l.Deduper
+	l.Mtx.RUnlock()
+	if d != nil {
+		d.Flush()
+	}
+}
+
+// the follow-up record has the meta of the repeated record
+func (l *Logger) logRepeat(r dedup.Repeat) {
+	var m = MF{}
+	for k, v := range r.Meta {
+		m[k] = v
+	}
+	m["log_num"] = shared.GetNextLogNum()
+	m["repeated"] = MF{
+		"count":       r.Count,
+		"duration_ms": r.Last.Sub(r.First).Milliseconds(),
+	}
+	var args = []interface{}{r.Message()}
+	l.writeFormatted(time.Now(), r.Level, NewMetaFields(&m), &args)
+}
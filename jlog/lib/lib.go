@@ -1,22 +1,24 @@
 package lib
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	uuid "github.com/google/uuid"
 	au "github.com/oresoftware/json-logging/jlog/au"
+	"github.com/oresoftware/json-logging/jlog/dedup"
 	hlpr "github.com/oresoftware/json-logging/jlog/helper"
 	ll "github.com/oresoftware/json-logging/jlog/level"
+	"github.com/oresoftware/json-logging/jlog/sample"
 	"github.com/oresoftware/json-logging/jlog/shared"
 	"github.com/oresoftware/json-logging/jlog/stack"
 	"github.com/oresoftware/json-logging/jlog/writer"
+	"io"
 	"log"
-	"math"
 	"os"
 	"reflect"
-	"runtime"
 	"runtime/debug"
 	"sort"
 	"strconv"
@@ -25,7 +27,6 @@ import (
 	"time"
 	// "unsafe"
 	"github.com/logrusorgru/aurora/v4"
-	"unsafe"
 	//jsoniter "github.com/json-iterator/go"
 	"bytes"
 	"github.com/mailru/easyjson"
@@ -80,7 +81,31 @@ type Logger struct {
 	EnvPrefix     string
 	LogLevel      ll.LogLevel
 	File          *os.File
+	Output        io.Writer // takes precedence over File, can be any io.Writer
 	IsShowLocalTZ bool
+	// Name is the dotted name of the logger, eg db.pool, a level set for it or a prefix overrides LogLevel (see Named)
+	Name string
+	// FlushOnCritical flushes/syncs the output before Critical returns, so crash logs are not lost
+	FlushOnCritical bool
+	// IsShowCaller adds the file:line:function of the logging call to the meta fields, under "caller"
+	IsShowCaller bool
+	// StackParams sets the depth and the package filters of stack traces
+	StackParams StackParams
+	// PanicParams says what Recover/Go do after logging a panic
+	PanicParams PanicParams
+	// Sampler drops records when sampling is on (see SetSampling), it is shared with child loggers
+	Sampler *sample.Sampler
+	// Deduper swallows repeated records when dedup is on (see SetDedupWindow), it is shared with child loggers
+	Deduper *dedup.Deduper
+	// records at this level and above get a StackTrace arg, the zero value means ERROR unless set with SetStackTraceLevel
+	StackTraceLevel ll.LogLevel
+	// stackLevelSet is true once SetStackTraceLevel is called, so TRACE can be told apart from unset
+	stackLevelSet bool
+	// a child logger follows the level of its parent until SetLogLevel is called on it, see Level
+	levelParent *Logger
+	// child and locked loggers write to the output of the logger they came from, so Close leaves it open,
+	// until they are given one of their own (the same calls which register an owner, see shared.RegisterOwner)
+	sharesOutput bool
 }
 
 type LoggerParams struct {
@@ -95,7 +120,21 @@ type LoggerParams struct {
 	EnvPrefix     string
 	LogLevel      ll.LogLevel
 	File          *os.File
+	Output        io.Writer // takes precedence over File, can be any io.Writer
 	IsShowLocalTZ bool
+	// Name is the dotted name of the logger, eg db.pool, a level set for it or a prefix overrides LogLevel (see Named)
+	Name string
+	// FlushOnCritical flushes/syncs the output before Critical returns, so crash logs are not lost
+	FlushOnCritical bool
+	// IsShowCaller adds the file:line:function of the logging call to the meta fields, under "caller"
+	IsShowCaller bool
+	// StackParams sets the depth and the package filters of stack traces
+	StackParams StackParams
+	// PanicParams says what Recover/Go do after logging a panic
+	PanicParams PanicParams
+	// StackTraceLevel attaches a stack trace to records at this level and above, unset (TRACE) means ERROR,
+	// call SetStackTraceLevel(ll.TRACE) to get one on every record
+	StackTraceLevel ll.LogLevel
 }
 
 func NewLogger(p LoggerParams) *Logger {
@@ -127,6 +166,11 @@ func NewLogger(p LoggerParams) *Logger {
 		isLoggingJson = true
 	}
 
+	// other writers are not terminals, so they get JSON like mult.AddOutput
+	if _, ok := p.Output.(*os.File); p.Output != nil && !ok {
+		isLoggingJson = true
+	}
+
 	if os.Getenv("jlog_log_json") == "no" {
 		isLoggingJson = false
 	}
@@ -147,14 +191,14 @@ func NewLogger(p LoggerParams) *Logger {
 			value := parts[1]
 			if strings.HasPrefix(key, p.EnvPrefix) {
 				result := strings.TrimPrefix(key, p.EnvPrefix)
-				(*metaFields.m)[result] = value
+				(*metaFields.Map())[result] = value
 			}
 		}
 	}
 
-	if p.MetaFields != nil && p.MetaFields.m != nil {
-		for k, v := range *p.MetaFields.m {
-			(*metaFields.m)[k] = v
+	if p.MetaFields != nil && p.MetaFields.Map() != nil {
+		for k, v := range *p.MetaFields.Map() {
+			(*metaFields.Map())[k] = v
 		}
 	}
 
@@ -163,20 +207,29 @@ func NewLogger(p LoggerParams) *Logger {
 		appName = p.AppName
 	}
 
-	return &Logger{
-		Mtx:           sync.RWMutex{},
-		AppName:       appName,
-		IsLoggingJSON: isLoggingJson,
-		HostName:      hostName,
-		ForceJSON:     p.ForceJSON,
-		ForceNonJSON:  p.ForceNonJSON,
-		TimeZone:      p.TimeZone,
-		MetaFields:    metaFields,
-		LockUuid:      p.LockUuid,
-		EnvPrefix:     p.EnvPrefix,
-		LogLevel:      p.LogLevel,
-		File:          file,
-	}
+	var l = &Logger{
+		Mtx:             sync.RWMutex{},
+		AppName:         appName,
+		IsLoggingJSON:   isLoggingJson,
+		HostName:        hostName,
+		ForceJSON:       p.ForceJSON,
+		ForceNonJSON:    p.ForceNonJSON,
+		TimeZone:        p.TimeZone,
+		MetaFields:      metaFields,
+		LockUuid:        p.LockUuid,
+		EnvPrefix:       p.EnvPrefix,
+		LogLevel:        p.LogLevel,
+		Name:            p.Name,
+		File:            file,
+		Output:          p.Output,
+		FlushOnCritical: p.FlushOnCritical,
+		IsShowCaller:    p.IsShowCaller,
+		StackParams:     p.StackParams,
+		StackTraceLevel: p.StackTraceLevel,
+		PanicParams:     p.PanicParams,
+	}
+	shared.RegisterOwner(l, l.output())
+	return l
 }
 
 func NewBasicLogger(AppName string, envTokenPrefix string, level ll.LogLevel) *Logger {
@@ -197,55 +250,27 @@ func CreateLogger(AppName string) *Logger {
 //	return New(AppName, forceJSON, hostName, envTokenPrefix)
 // }
 
-type KV struct {
-	Key   string
-	Value interface{}
-	*metaFieldsMarker
-}
-
-type M = map[string]interface{}
-type L = []KV
+type KV = shared.KV
+type M = shared.M
+type L = shared.L
+type MF = shared.MF
+type MetaFields = shared.MetaFields
+type LogId = shared.LogId
+type ErrorId = shared.ErrorId
+type Opts = shared.Opts
+type StackTrace = shared.StackTrace
+type Frame = hlpr.Frame
+type StackParams = hlpr.StackParams
+type PanicParams = shared.PanicParams
+type SampleParams = sample.Params
+type SampleRule = sample.Rule
 
 func NewMetaFields(m *MF) *MetaFields {
-	return &MetaFields{
-		metaFieldsMarker: mfMarker,
-		UniqueMarker:     "UniqueMarker(Brand)",
-		m:                m,
-	}
-}
-
-type logIdMarker struct{}
-
-var myLogIdMarker = &logIdMarker{}
-
-type LogId struct {
-	*logIdMarker
-	Val string
-}
-
-func (x *LogId) GetLogId(isHyperLink bool) string {
-	// fmt.Println("\\e]8;;http://example.com\aThis is the link\\e]8;;\\e\\")
-	// fmt.Println(fmt.Sprintf("\033]8;;%s\033\\%s\033]8;;\033\\", "https://linkedin.com", "Go to Linked"))
-
-	// fmt.Println(fmt.Sprintf("\033]8;;%s\033\\%s\033]8;;\033\\", "Go to x", "Go to XX"))
-
-	if false && isHyperLink {
-		// return au.Col.Blue("xyz1").Hyperlink(fmt.Sprintf("foobarbas", x.Val)).HyperlinkTarget()
-		return au.Col.Hyperlink("foo", "https://foo.com").String()
-		return au.Col.Hyperlink("foo", "https://foo.com").HyperlinkTarget()
-		// return au.Col.Blue("(Goto -> LogId)").Hyperlink(fmt.Sprintf("http://vibeirl.com/dev/links?%s", x.Val)).HyperlinkTarget()
-	} else {
-		// return fmt.Sprintf("(log-id:'%s')", x.Val)
-		return x.Val
-	}
-}
-
-func (x *LogId) IsLogId() bool {
-	return true
+	return shared.NewMetaFields(m)
 }
 
 func Id(v string) *LogId {
-	return &LogId{myLogIdMarker, v}
+	return shared.Id(v)
 }
 
 func (l *Logger) Id(v string) *LogId {
@@ -253,7 +278,7 @@ func (l *Logger) Id(v string) *LogId {
 }
 
 func levelEnabled(minLevel ll.LogLevel, level ll.LogLevel) bool {
-	return level >= minLevel
+	return level.AtLeast(minLevel)
 }
 
 func V(level ll.LogLevel) bool {
@@ -269,9 +294,48 @@ func (l *Logger) V(level ll.LogLevel) bool {
 }
 
 func (l *Logger) IsLevelEnabled(level ll.LogLevel) bool {
+	return levelEnabled(l.EffectiveLevel(), level)
+}
+
+// EffectiveLevel is the level set for the logger's name (or the closest prefix), or else Level,
+// moved by shared.ShiftLevels.
+func (l *Logger) EffectiveLevel() ll.LogLevel {
+	l.Mtx.RLock()
+	var name = l.Name
+	l.Mtx.RUnlock()
+	var level = l.Level()
+	if v, ok := shared.NamedLevel(name); ok {
+		level = v
+	}
+	return shared.Shifted(level)
+}
+
+// Describe is a snapshot of the logger's levels and output.
+func (l *Logger) Describe() shared.LoggerInfo {
+	var level = l.EffectiveLevel()
+
 	l.Mtx.RLock()
 	defer l.Mtx.RUnlock()
-	return levelEnabled(l.LogLevel, level)
+	var info = shared.LoggerInfo{
+		App:     l.AppName,
+		Name:    l.Name,
+		Level:   level.String(),
+		Outputs: []shared.OutputInfo{shared.DescribeOutput(l.output(), level, l.IsLoggingJSON)},
+	}
+	if v, ok := shared.NamedLevel(l.Name); ok {
+		info.NamedLevel = v.String()
+	}
+	return info
+}
+
+// SetNamedLevel sets the level of the loggers with this name and the ones below it, eg db covers db.pool.
+func SetNamedLevel(name string, level ll.LogLevel) {
+	shared.SetNamedLevel(name, level)
+}
+
+// ClearNamedLevel removes a level set with SetNamedLevel or the jlog_levels env var.
+func ClearNamedLevel(name string) {
+	shared.ClearNamedLevel(name)
 }
 
 func (l *Logger) IsTraceEnabled() bool {
@@ -298,16 +362,24 @@ func (l *Logger) IsCriticalEnabled() bool {
 	return l.IsLevelEnabled(ll.CRITICAL)
 }
 
-func (l *Logger) outputFile() *os.File {
+func (l *Logger) outputFile() io.Writer {
 	l.Mtx.RLock()
 	defer l.Mtx.RUnlock()
+	return l.output()
+}
+
+// must hold l.Mtx
+func (l *Logger) output() io.Writer {
+	if l.Output != nil {
+		return l.Output
+	}
 	if l.File == nil {
 		return os.Stdout
 	}
 	return l.File
 }
 
-func (l *Logger) writeOutput(f *os.File, b []byte) {
+func (l *Logger) writeOutput(f io.Writer, b []byte) {
 	if f == nil {
 		f = os.Stdout
 	}
@@ -326,28 +398,94 @@ func (l *Logger) writeOutput(f *os.File, b []byte) {
 	}
 }
 
+// writeRecord is writeOutput for log records, the level is passed on to level-aware writers (async)
+func (l *Logger) writeRecord(f io.Writer, level ll.LogLevel, b []byte) {
+	if f == nil {
+		f = os.Stdout
+	}
+
+	l.Mtx.RLock()
+	isLockedLogger := l.LockUuid != ""
+	l.Mtx.RUnlock()
+
+	if !isLockedLogger {
+		shared.M1.RLock()
+		defer shared.M1.RUnlock()
+	}
+
+	if _, err := writer.WriteLevel(f, level, b); err != nil {
+		writeToStderr("json-logging: could not write log output:", err)
+	}
+}
+
+// SetAsync turns on async mode: records are serialized on the calling goroutine,
+// queued in a bounded ring buffer and written in batches by a background goroutine.
+// p.Writer defaults to the current output.
+func (l *Logger) SetAsync(p writer.AsyncParams) *Logger {
+	l.Mtx.Lock()
+	defer l.Mtx.Unlock()
+	if _, ok := l.output().(*writer.AsyncWriter); ok {
+		return l
+	}
+	if p.Writer == nil {
+		p.Writer = l.output()
+	}
+	l.Output = writer.NewAsyncWriter(p)
+	shared.RegisterOwner(l, l.Output)
+	return l
+}
+
+// AsyncStats returns the queue counters (including dropped records) if async mode is on.
+func (l *Logger) AsyncStats() writer.AsyncStats {
+	if aw, ok := l.outputFile().(*writer.AsyncWriter); ok {
+		return aw.Stats()
+	}
+	return writer.AsyncStats{}
+}
+
 func (l *Logger) NewLoggerWithLock() (*Logger, func()) {
 	shared.M1.Lock()
 	var id = uuid.New().String()
 	lockStack.Push(&stack.StackItem{
 		Id: id,
 	})
+	// the queues are drained and the locked logger skips them, so that nothing else gets written
+	// until the lock is released, the other loggers are blocked before they can queue a record
+	if err := writer.FlushAll(context.Background()); err != nil {
+		writeToStderr("json-logging: could not flush async outputs:", err)
+	}
+	var level = l.Level()
 	l.Mtx.RLock()
+	var output = l.Output
+	if aw, ok := output.(*writer.AsyncWriter); ok {
+		output = aw.Destination()
+	}
 	var z = Logger{
-		Mtx:           sync.RWMutex{},
-		AppName:       l.AppName,
-		IsLoggingJSON: l.IsLoggingJSON,
-		HighPerf:      l.HighPerf,
-		HostName:      l.HostName,
-		ForceJSON:     l.ForceJSON,
-		ForceNonJSON:  l.ForceNonJSON,
-		TimeZone:      l.TimeZone,
-		MetaFields:    l.MetaFields,
-		LockUuid:      id,
-		EnvPrefix:     l.EnvPrefix,
-		LogLevel:      l.LogLevel,
-		File:          l.File,
-		IsShowLocalTZ: l.IsShowLocalTZ,
+		Mtx:             sync.RWMutex{},
+		AppName:         l.AppName,
+		IsLoggingJSON:   l.IsLoggingJSON,
+		HighPerf:        l.HighPerf,
+		HostName:        l.HostName,
+		ForceJSON:       l.ForceJSON,
+		ForceNonJSON:    l.ForceNonJSON,
+		TimeZone:        l.TimeZone,
+		MetaFields:      l.MetaFields,
+		LockUuid:        id,
+		EnvPrefix:       l.EnvPrefix,
+		LogLevel:        level,
+		Name:            l.Name,
+		File:            l.File,
+		Output:          output,
+		IsShowLocalTZ:   l.IsShowLocalTZ,
+		FlushOnCritical: l.FlushOnCritical,
+		IsShowCaller:    l.IsShowCaller,
+		StackParams:     l.StackParams,
+		StackTraceLevel: l.StackTraceLevel,
+		stackLevelSet:   l.stackLevelSet,
+		PanicParams:     l.PanicParams,
+		Sampler:         l.Sampler,
+		Deduper:         l.Deduper,
+		sharesOutput:    true,
 	}
 	l.Mtx.RUnlock()
 	return &z, z.unlock
@@ -373,7 +511,7 @@ func (l *Logger) SetEnvPrefix(s string) *Logger {
 			value := parts[1]
 			if strings.HasPrefix(key, l.EnvPrefix) {
 				result := strings.TrimPrefix(key, l.EnvPrefix)
-				(*l.MetaFields.m)[result] = value
+				(*l.MetaFields.Map())[result] = value
 			}
 		}
 	}
@@ -406,24 +544,60 @@ func (l *Logger) SetToDisplayLocalTZ() *Logger {
 }
 
 func (l *Logger) SetOutputFile(f *os.File) *Logger {
-	// TODO: use lock to set this
 	l.Mtx.Lock()
 	defer l.Mtx.Unlock()
 	l.File = f
+	l.Output = nil
+	l.sharesOutput = false
+	shared.RegisterOwner(l, f)
+	return l
+}
+
+// SetOutput sends the output to any io.Writer, eg a bytes.Buffer, a net.Conn, a gzip.Writer.
+// If the writer is a file, it shares the per-inode lock with other writers to the same file.
+// Other writers are not terminals, so they get JSON, like mult.AddOutput.
+func (l *Logger) SetOutput(w io.Writer) *Logger {
+	l.Mtx.Lock()
+	defer l.Mtx.Unlock()
+	if f, ok := w.(*os.File); ok || w == nil {
+		l.File = f
+		l.Output = nil
+		l.sharesOutput = false
+		shared.RegisterOwner(l, f)
+		return l
+	}
+	l.Output = w
+	l.IsLoggingJSON = true
+	l.sharesOutput = false
+	shared.RegisterOwner(l, w)
 	return l
 }
 
+// SetLogLevel sets the level of the logger, and of its children which do not have a level of their own.
 func (l *Logger) SetLogLevel(f ll.LogLevel) *Logger {
 	l.Mtx.Lock()
 	defer l.Mtx.Unlock()
 	l.LogLevel = f
+	l.levelParent = nil
 	return l
 }
 
+// Level is LogLevel, or the level of the parent for a child logger without a level of its own,
+// before named levels and shared.ShiftLevels, see EffectiveLevel.
+func (l *Logger) Level() ll.LogLevel {
+	l.Mtx.RLock()
+	var level, parent = l.LogLevel, l.levelParent
+	l.Mtx.RUnlock()
+	if parent != nil {
+		return parent.Level()
+	}
+	return level
+}
+
 func (l *Logger) AddMetaField(s string, v interface{}) *Logger {
 	l.Mtx.Lock()
 	defer l.Mtx.Unlock()
-	(*l.MetaFields.m)[s] = v
+	(*l.MetaFields.Map())[s] = v
 	return l
 }
 
@@ -455,6 +629,14 @@ func (l *Logger) SetHostName(h string) *Logger {
 	return l
 }
 
+// SetShowCaller turns on/off recording the file, line and function of each logging call.
+func (l *Logger) SetShowCaller(b bool) *Logger {
+	l.Mtx.Lock()
+	defer l.Mtx.Unlock()
+	l.IsShowCaller = b
+	return l
+}
+
 func (l *Logger) unlock() {
 	var peek, err = lockStack.Peek()
 
@@ -486,7 +668,7 @@ func (l *Logger) Child(m *map[string]interface{}) *Logger {
 	defer l.Mtx.RUnlock()
 
 	var z = make(map[string]interface{})
-	for k, v := range *l.MetaFields.m {
+	for k, v := range *l.MetaFields.Map() {
 		z[k] = hlpr.CopyAndDereference(v)
 	}
 
@@ -495,20 +677,32 @@ func (l *Logger) Child(m *map[string]interface{}) *Logger {
 	}
 
 	return &Logger{
-		Mtx:           sync.RWMutex{},
-		AppName:       l.AppName,
-		IsLoggingJSON: l.IsLoggingJSON,
-		HighPerf:      l.HighPerf,
-		HostName:      l.HostName,
-		ForceJSON:     l.ForceJSON,
-		ForceNonJSON:  l.ForceNonJSON,
-		TimeZone:      l.TimeZone,
-		MetaFields:    NewMetaFields(&z),
-		LockUuid:      l.LockUuid,
-		EnvPrefix:     l.EnvPrefix,
-		LogLevel:      l.LogLevel,
-		File:          l.File,
-		IsShowLocalTZ: l.IsShowLocalTZ,
+		Mtx:             sync.RWMutex{},
+		AppName:         l.AppName,
+		IsLoggingJSON:   l.IsLoggingJSON,
+		HighPerf:        l.HighPerf,
+		HostName:        l.HostName,
+		ForceJSON:       l.ForceJSON,
+		ForceNonJSON:    l.ForceNonJSON,
+		TimeZone:        l.TimeZone,
+		MetaFields:      NewMetaFields(&z),
+		LockUuid:        l.LockUuid,
+		EnvPrefix:       l.EnvPrefix,
+		LogLevel:        l.LogLevel,
+		levelParent:     l,
+		sharesOutput:    true,
+		Name:            l.Name,
+		File:            l.File,
+		Output:          l.Output,
+		IsShowLocalTZ:   l.IsShowLocalTZ,
+		FlushOnCritical: l.FlushOnCritical,
+		IsShowCaller:    l.IsShowCaller,
+		StackParams:     l.StackParams,
+		StackTraceLevel: l.StackTraceLevel,
+		stackLevelSet:   l.stackLevelSet,
+		PanicParams:     l.PanicParams,
+		Sampler:         l.Sampler,
+		Deduper:         l.Deduper,
 	}
 }
 
@@ -534,9 +728,37 @@ func (l *Logger) Create(m *map[string]interface{}) *Logger {
 	return l.Child(m)
 }
 
+var (
+	_ shared.Logger     = (*Logger)(nil)
+	_ shared.ChildMaker = (*Logger)(nil)
+	_ shared.Namer      = (*Logger)(nil)
+	_ shared.Locker     = (*Logger)(nil)
+	_ shared.Describer  = (*Logger)(nil)
+	_ shared.Flusher    = (*Logger)(nil)
+	_ io.Closer         = (*Logger)(nil)
+	_ shared.Recoverer  = (*Logger)(nil)
+	_ shared.Printer    = (*Logger)(nil)
+)
+
+func (l *Logger) ChildLogger(m *map[string]interface{}) shared.Logger {
+	return l.Child(m)
+}
+
+func (l *Logger) TagPairLogger(k string, v interface{}) shared.Logger {
+	return l.TagPair(k, v)
+}
+
+func (l *Logger) NamedLogger(name string) shared.Logger {
+	return l.Named(name)
+}
+
+func (l *Logger) LockedLogger() (shared.Logger, func()) {
+	return l.NewLoggerWithLock()
+}
+
 func (l *Logger) writeToFile(ts time.Time, level ll.LogLevel, m *MetaFields, args *[]interface{}) {
 	b := l.getPrettyString(ts, level, m, args)
-	l.writeOutput(l.outputFile(), []byte(b.String()))
+	l.writeRecord(l.outputFile(), level, []byte(b.String()))
 	// _, err := io.Copy(l.File, b.)  // TODO: copy to file, instead of buffering b.String()
 }
 
@@ -548,7 +770,6 @@ func (l *Logger) getPrettyString(ts time.Time, level ll.LogLevel, m *MetaFields,
 	timeZone := l.TimeZone
 	l.Mtx.RUnlock()
 
-	var b strings.Builder
 	date := ts.UTC().Format("15:04:05.000000")
 
 	if isShowLocalTZ {
@@ -558,31 +779,34 @@ func (l *Logger) getPrettyString(ts time.Time, level ll.LogLevel, m *MetaFields,
 			date = ts.Local().Format("15:04:05.000000")
 		}
 	}
+	// a zero time is left out, eg for slog records without one
+	if ts.IsZero() {
+		date = ""
+	}
 
-	stylizedLevel := "<undefined>"
-
-	switch level {
-
-	case ll.ERROR:
-		stylizedLevel = au.Col.Underline(au.Col.Bold(au.Col.Red("ERROR"))).String()
-		break
+	return prettyString(date, level, appName, m, args, false)
+}
 
-	case ll.WARN:
-		stylizedLevel = au.Col.Magenta("WARN").String()
-		break
+// PrettyString renders a record the same way the logger does for a terminal,
+// the jlog command uses it to render decoded @bunion records.
+// If isShowMeta is true, the meta fields other than log_id/log_num are rendered too.
+func PrettyString(date string, level ll.LogLevel, appName string, m *MetaFields, args []interface{}, isShowMeta bool) string {
+	if m == nil || m.Map() == nil {
+		m = NewMetaFields(&MF{})
+	}
+	return prettyString(date, level, appName, m, &args, isShowMeta).String()
+}
 
-	case ll.DEBUG:
-		stylizedLevel = au.Col.Bold("DEBUG").String()
-		break
+// CallerLink renders a caller as a dimmed file:line, which is a hyperlink to the file on terminals that support it.
+func CallerLink(c *hlpr.Caller) string {
+	var text = c.ShortFile() + ":" + strconv.Itoa(c.Line)
+	return au.Col.Faint(au.Col.Hyperlink(text, "file://"+c.File)).String()
+}
 
-	case ll.INFO:
-		stylizedLevel = au.Col.Gray(12, "INFO").String()
-		break
+func prettyString(date string, level ll.LogLevel, appName string, m *MetaFields, args *[]interface{}, isShowMeta bool) *strings.Builder {
 
-	case ll.TRACE:
-		stylizedLevel = au.Col.Gray(4, "TRACE").String()
-		break
-	}
+	var b strings.Builder
+	stylizedLevel := level.Pretty()
 
 	b.WriteString(au.Col.Gray(9, date).String())
 	b.WriteString(" ")
@@ -591,16 +815,34 @@ func (l *Logger) getPrettyString(ts time.Time, level ll.LogLevel, m *MetaFields,
 	b.WriteString(au.Col.Gray(12, "app:").String())
 	b.WriteString(au.Col.Italic(appName).String())
 	b.WriteString(" ")
-	if v, ok := (*m.m)["log_id"]; ok {
+	if v, ok := (*m.Map())["log_id"]; ok {
 		//b.WriteString(fmt.Sprintf("(log-id:%s) ", v))
 		b.WriteString(fmt.Sprintf("(%s%s) ", aurora.Bold("log-id:").String(), getLastXChars(12, v)))
 	}
 
-	if v, ok := (*m.m)["log_num"]; ok {
+	if v, ok := (*m.Map())["log_num"]; ok {
 		//b.WriteString(fmt.Sprintf("(log-id:%s) ", v))
 		b.WriteString(fmt.Sprintf("(%s%v) ", aurora.Bold("log-num:").String(), v))
 	}
 
+	if c, ok := hlpr.CallerFromMeta((*m.Map())["caller"]); ok {
+		b.WriteString(CallerLink(c))
+		b.WriteString(" ")
+	}
+
+	if isShowMeta {
+		var keys = make([]string, 0, len(*m.Map()))
+		for k := range *m.Map() {
+			if k != "log_id" && k != "log_num" && k != "caller" {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			b.WriteString(fmt.Sprintf("%s%v ", au.Col.Gray(12, k+":").String(), (*m.Map())[k]))
+		}
+	}
+
 	size := 0
 
 	for _, v := range *args {
@@ -612,6 +854,16 @@ func (l *Logger) getPrettyString(ts time.Time, level ll.LogLevel, m *MetaFields,
 			continue
 		}
 
+		if frames, ok := hlpr.FramesFromArg(v); ok {
+			b.WriteString(hlpr.PrettyFrames(frames))
+			continue
+		}
+
+		if e, ok := hlpr.ErrorFromArg(v); ok {
+			b.WriteString(hlpr.PrettyError(e) + " ")
+			continue
+		}
+
 		if &v == nil {
 			b.WriteString(fmt.Sprintf("<nil 2> (%T)", v))
 			continue
@@ -685,7 +937,7 @@ func (l *Logger) getPrettyString(ts time.Time, level ll.LogLevel, m *MetaFields,
 			writeToStderr("771c710b-aba2-46ef-9126-c26d3dfe7925", err)
 		}
 
-		if !primitive && (level == ll.TRACE || level == ll.DEBUG) {
+		if !primitive && !level.AtLeast(ll.INFO) {
 
 			if _, err := b.WriteString("\n"); err != nil {
 				writeToStderr("18614292-658f-42a5-81e7-593e941ea857", err)
@@ -822,26 +1074,6 @@ func marshalCustomArray(arr []interface{}) ([]byte, error) {
 	return b.Bytes(), nil // Convert buffer to bytes and return
 }
 
-var ioChan = make(chan func())
-var ioChan2 = make(chan func())
-
-func init() {
-	go func() {
-		for {
-			fn := <-ioChan
-			fn()
-		}
-	}()
-
-	go func() {
-		for {
-			fn := <-ioChan2
-			fn()
-		}
-	}()
-
-}
-
 func (l *Logger) writeJSON(ts time.Time, level ll.LogLevel, mf *MetaFields, args *[]interface{}) {
 
 	l.Mtx.RLock()
@@ -849,13 +1081,9 @@ func (l *Logger) writeJSON(ts time.Time, level ll.LogLevel, mf *MetaFields, args
 	hostName := l.HostName
 	isShowLocalTZ := l.IsShowLocalTZ
 	timeZone := l.TimeZone
-	file := l.File
+	file := l.output()
 	l.Mtx.RUnlock()
 
-	if file == nil {
-		file = os.Stdout
-	}
-
 	date := ts.UTC().Format("2006-01-02 15:04:05.000000")
 
 	if isShowLocalTZ {
@@ -865,19 +1093,26 @@ func (l *Logger) writeJSON(ts time.Time, level ll.LogLevel, mf *MetaFields, args
 			date = ts.Local().Format("2006-01-02 15:04:05.000000")
 		}
 	}
-	var strLevel = shared.LevelToString[level]
+	// a zero time is left out, eg for slog records without one
+	if ts.IsZero() {
+		date = ""
+	}
+	var strLevel = level.JSONName()
 	var pid = shared.PID
 
 	if mf == nil {
 		mf = NewMetaFields(&MF{})
 	}
 
-	buf, err := json.Marshal([8]interface{}{"@bunion:v1", appName, strLevel, pid, hostName, date, mf.m, *args})
+	buf, err := json.Marshal([8]interface{}{"@bunion:v1", appName, strLevel, pid, hostName, date, mf.Map(), *args})
 
 	if err != nil {
 
-		_, callerFile, line, _ := runtime.Caller(3)
-		DefaultLogger.Warn("json-logging: 1: could not marshal the slice:", err.Error(), "file://"+callerFile+":"+strconv.Itoa(line))
+		var where = "<unknown caller>"
+		if c := hlpr.GetCaller(0); c != nil {
+			where = "file://" + c.String()
+		}
+		writeToStderr("json-logging: 1: could not marshal the slice:", err.Error(), where)
 
 		var cache = map[uintptr]interface{}{}
 		var cleaned = make([]interface{}, 0, len(*args))
@@ -888,7 +1123,7 @@ func (l *Logger) writeJSON(ts time.Time, level ll.LogLevel, mf *MetaFields, args
 			cleaned = append(cleaned, c)
 		}
 
-		buf, err = json.Marshal([8]interface{}{"@bunion:v1", appName, strLevel, pid, hostName, date, mf.m, cleaned})
+		buf, err = json.Marshal([8]interface{}{"@bunion:v1", appName, strLevel, pid, hostName, date, mf.Map(), cleaned})
 
 		if err != nil {
 			writeToStderr(errors.New("Json-Logging: 2: could not marshal the slice: " + err.Error()))
@@ -897,10 +1132,18 @@ func (l *Logger) writeJSON(ts time.Time, level ll.LogLevel, mf *MetaFields, args
 	}
 
 	buf = append(buf, '\n')
-	l.writeOutput(file, buf)
+	l.writeRecord(file, level, buf)
 }
 
 func (l *Logger) writeSwitch(time time.Time, level ll.LogLevel, m *MetaFields, args *[]interface{}) {
+	if l.isRepeat(level, m, args) {
+		return
+	}
+	l.writeFormatted(time, level, m, args)
+}
+
+// writeFormatted writes a record in the configured format, without dedup
+func (l *Logger) writeFormatted(time time.Time, level ll.LogLevel, m *MetaFields, args *[]interface{}) {
 	l.Mtx.RLock()
 	isLoggingJSON := l.IsLoggingJSON
 	l.Mtx.RUnlock()
@@ -965,9 +1208,7 @@ func (l *Logger) RawJSON(args ...interface{}) {
 	l.writeOutput(os.Stdout, b.Bytes())
 }
 
-type Stringer interface {
-	String() string
-}
+type Stringer = hlpr.Stringer
 
 type LogItem struct {
 	AsString  string
@@ -975,433 +1216,19 @@ type LogItem struct {
 	Value     interface{}
 }
 
-type ArrayVal struct {
-	GoType      string
-	TrueLen     int
-	IsTruncated bool
-	Val         []interface{}
-}
-
-type MapVal struct {
-	GoType       string
-	TrueKeyCount int
-	IsTruncated  bool
-	Val          map[string]interface{}
-}
-
-type EmptyVal struct {
-	EmptyVal bool
-}
-
-func doMap(v interface{}, val reflect.Value) *MapVal {
-
-	if x, ok := v.(MapVal); ok {
-		return &x
-	}
-
-	if x, ok := v.(*MapVal); ok {
-		return x
-	}
-
-	if !val.IsValid() {
-		return nil
-	}
-
-	var z = MapVal{
-		GoType:       "<unknown>",
-		TrueKeyCount: 0,
-		IsTruncated:  false,
-		Val:          nil,
-	}
-
-	len := val.Len()
-	z.TrueKeyCount = len
-	z.GoType = val.Type().String()
-
-	keyToRetrieve := "JLogMarker"
-	keyType := val.Type().Key()
-	markerKey := reflect.ValueOf(keyToRetrieve)
-
-	if markerKey.Type().AssignableTo(keyType) {
-		keyValue := val.MapIndex(markerKey)
-		if keyValue.IsValid() {
-			return &z
-		}
-	} else if markerKey.Type().ConvertibleTo(keyType) {
-		keyValue := val.MapIndex(markerKey.Convert(keyType))
-		if keyValue.IsValid() {
-			return &z
-		}
-	}
-
-	keys := val.MapKeys()
-
-	min := int(math.Min(float64(len), float64(25)))
-	if min < len {
-		z.IsTruncated = true
-	}
-
-	z.Val = map[string]interface{}{}
-
-	i := 0
-	for _, key := range keys {
-		if i++; i > min {
-			break
-		}
-		var el = val.MapIndex(key)
-		if el.IsValid() && el.CanInterface() {
-			z.Val[fmt.Sprintf("%v", key)] = getInspectableVal(el.Interface(), el, 0, 1)
-		} else {
-			z.Val[fmt.Sprintf("%v", key)] = nil
-		}
-	}
-
-	// for i := 0; i < min; i++ {
-	//  el := val.Index(i)
-	//  if el.IsValid() {
-	//    z.Val[i] = getInspectableVal(el.Interface(), el, 0, 1)
-	//  } else {
-	//    // Handle the case where the value is nil
-	//    z.Val[i] = nil // or any default value you want
-	//  }
-	// }
-
-	return &z
-}
-
-func doArray(v interface{}, rv reflect.Value) *ArrayVal {
-
-	if x, ok := v.(ArrayVal); ok {
-		return &x
-	}
-
-	if x, ok := v.(*ArrayVal); ok {
-		return x
-	}
-
-	var z = ArrayVal{
-		TrueLen:     0,
-		IsTruncated: false,
-		Val:         nil,
-		GoType:      "<unknown>",
-	}
-
-	len := rv.Len()
-	z.TrueLen = len
-
-	min := int(math.Min(float64(len), float64(40)))
-	if min < len {
-		z.IsTruncated = true
-	}
-
-	z.Val = make([]interface{}, min)
-
-	for i := 0; i < min; i++ {
-		el := rv.Index(i)
-		if el.IsValid() {
-			z.GoType = fmt.Sprintf("%s", el.Type().String())
-			inf := el.Interface()
-			// z.GoType = fmt.Sprintf("%T", inf)
-			z.Val[i] = getInspectableVal(inf, el, 0, 1)
-		} else {
-			// Handle the case where the value is nil
-			z.Val[i] = nil // or any default value you want
-		}
-	}
-
-	// TODO: add the 3 last original elements to end of new list, if space permits
-
-	// for i := 0; i < 3; i++ {
-	//  z.Val = append(z.Val, EmptyVal{EmptyVal: true})
-	// }
-	//
-	// var b = math.Max(3, float64(min-len))
-	//
-	// for i := int(b); i >= 0; i-- {
-	//  z.Val = append(z.Val, getInspectableVal(rv.Index(len-1-i).Interface(), 0))
-	// }
-
-	return &z
-}
-
-type VibeInspectStr interface {
-	ToString() string
-}
-
-type VibeInspectInt interface {
-	ToInt() int
-}
-
-type VibeInspectBool interface {
-	ToBool() bool
-}
-
-type UnkVal struct {
-	GoType   string
-	Val      interface{}
-	ValAsStr string
-}
+type ArrayVal = hlpr.ArrayVal
+type MapVal = hlpr.MapVal
+type EmptyVal = hlpr.EmptyVal
+type VibeInspectStr = hlpr.VibeInspectStr
+type VibeInspectInt = hlpr.VibeInspectInt
+type VibeInspectBool = hlpr.VibeInspectBool
+type UnkVal = hlpr.UnkVal
 
 func getInspectableVal(obj interface{}, rv reflect.Value, depth int, count int) interface{} {
-	// /
-	// var rv = reflect.ValueOf(obj)
-
-	if depth > 16 {
-		return fmt.Sprintf("(go:max-depth:%T)", obj)
-	}
-
-	if count > 11 {
-		return obj
-	}
-
-	if !rv.IsValid() {
-		// Handle invalid reflection value (e.g., nil pointer)
-		return nil
-	}
-
-	var v = rv.Interface()
-
-	if v == nil {
-		return v
-	}
-
-	if rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
-
-		if rv.IsNil() {
-			// Handle nil interface value
-			return nil
-		}
-
-		if !rv.IsValid() {
-			return nil
-		}
-
-		rv = rv.Elem()
-
-		if !rv.IsValid() {
-			return nil
-		}
-
-		if rv.CanInterface() {
-			return getInspectableVal(rv.Interface(), rv, depth, count+1)
-		}
-
-	}
-
-	if !rv.CanInterface() {
-		return v
-	}
-
-	v = rv.Interface()
-
-	if v == nil {
-		return nil
-	}
-
-	if x, ok := v.(string); ok {
-		return x
-	}
-
-	if x, ok := v.(int); ok {
-		return x
-	}
-
-	if x, ok := v.(bool); ok {
-		return x
-	}
-
-	// if depth > 3 {
-	//  return v
-	// }
-
-	if !rv.IsValid() {
-		return nil
-	}
-
-	switch rv.Kind() {
-	//
-	case reflect.Slice:
-		if rv.Type().Elem().Kind() == reflect.Uint8 {
-			if z, ok := v.([]byte); ok {
-				return string(z)
-			}
-			return v
-		}
-		return doArray(v, rv)
-
-	case reflect.Array:
-		if rv.Type().Elem().Kind() == reflect.Uint8 {
-			if z, ok := v.([]byte); ok {
-				return string(z)
-			}
-			return v
-		}
-		return doArray(v, rv)
-	}
-
-	if rv.Kind() == reflect.Func {
-		return fmt.Sprintf("(func())")
-	}
-
-	if rv.Kind() == reflect.Chan {
-		if rv.IsValid() && rv.CanInterface() {
-			return fmt.Sprintf("(chan (%v) %v)", rv.Type(), rv.Interface())
-		}
-		return fmt.Sprintf("(chan (%v) (%v) %+v)", rv.Type(), v, v)
-	}
-
-	if rv.Kind() == reflect.Map {
-		return doMap(v, rv)
-	}
-
-	if rv.Kind() != reflect.Struct {
-		// if it's a not a struct now
-		var str = fmt.Sprintf("(%v / %v)", v, rv.Type().String())
-		var t = fmt.Sprintf("(%T / %v)", v, rv.Type().String())
-		return &UnkVal{
-			GoType:   t,
-			Val:      v,
-			ValAsStr: str,
-		}
-	}
-
-	// it's a struct, so we can add metadata to it
-	var errStr = ""
-	var toString = ""
-
-	var typeStr = fmt.Sprintf("%T", v)
-
-	if rv.IsValid() {
-		typ := rv.Type()
-		z := typ.String()
-		if z != typeStr {
-			typeStr = fmt.Sprintf("(%s / %v / %s)", typeStr, typ, z)
-		}
-	}
-
-	if z, ok := v.(error); ok {
-		errStr = z.Error()
-	}
-
-	if z, ok := v.(Stringer); ok {
-		toString = z.String()
-	}
-
-	outResult := make(map[string]interface{})
-
-	if typeStr != "" {
-		outResult["+(GoType):"] = typeStr
-	}
-
-	if errStr != "" {
-		outResult["+(ErrStr):"] = errStr
-	}
-
-	if toString != "" && toString != errStr {
-		outResult["+(ToStr):"] = toString
-	}
-
-	innerResult := make(map[string]interface{})
-	outResult["+(Val):"] = innerResult
-
-	typ := rv.Type()
-
-	for i := 0; i < rv.NumField(); i++ {
-
-		field := rv.Field(i)
-		fieldName := typ.Field(i).Name
-
-		if !field.IsValid() {
-			innerResult[fieldName] = nil
-			continue
-		}
-
-		j := 0
-
-		for {
-
-			if j++; j > 9 {
-				// only try to deref so many times - perhaps it's a ptr to a ptr, etc
-				break
-			}
-
-			if !(field.Kind() == reflect.Ptr || field.Kind() == reflect.Interface) {
-				break
-			}
-
-			if !field.IsValid() {
-				innerResult[fieldName] = nil
-				break
-			}
-
-			if field.IsNil() {
-				innerResult[fieldName] = nil
-				break
-			}
-
-			field = field.Elem()
-
-			if !field.IsValid() {
-				innerResult[fieldName] = nil
-				break
-			}
-
-		}
-
-		if _, ok := innerResult[fieldName]; ok {
-			continue
-		}
-
-		if field.Kind() == reflect.Interface || field.Kind() == reflect.Ptr {
-
-			if field.IsNil() {
-				innerResult[fieldName] = nil
-				continue
-			}
-
-			if !field.IsValid() {
-				innerResult[fieldName] = nil
-				continue
-			}
-
-			field = field.Elem()
-
-			if !field.IsValid() {
-				innerResult[fieldName] = nil
-				continue
-			}
-
-			return fmt.Sprintf("yyy %T // %v", field.Interface(), field.Interface())
-		}
-
-		if field.CanInterface() {
-			innerResult[fieldName] = getInspectableVal(field.Interface(), field, depth+1, 1)
-			continue
-		}
-
-		innerResult[fieldName] = fmt.Sprintf("%v (Type: %s)", field.String(), field.Type().String())
-		continue
-
-		// f := rv.FieldByName(fieldName)
-
-		if field.CanAddr() {
-			field = reflect.NewAt(field.Type(), unsafe.Pointer(field.UnsafeAddr())).Elem()
-			//field = reflect.NewAt(field.Type(), field.Addr().UnsafePointer())
-		}
-
-		if field.IsValid() && field.CanInterface() {
-			innerResult[fieldName] = getInspectableVal(field.Interface(), field, depth+1, 1)
-		} else {
-			innerResult[fieldName] = fmt.Sprintf("%s", field.Type().String())
-		}
-
-		continue
-	}
-
-	return outResult
+	return hlpr.GetInspectableVal(obj, rv, depth, count)
 }
 
-func (l *Logger) getMetaFields(args *[]interface{}) (*MetaFields, []interface{}) {
+func (l *Logger) getMetaFields(args *[]interface{}) (*MetaFields, []interface{}, *Opts) {
 	// //
 	var newArgs = []interface{}{}
 	var mf = NewMetaFields(&MF{})
@@ -1409,30 +1236,51 @@ func (l *Logger) getMetaFields(args *[]interface{}) (*MetaFields, []interface{})
 	l.Mtx.RLock()
 	isLoggingJSON := l.IsLoggingJSON
 	highPerf := l.HighPerf
-	for k, v := range *l.MetaFields.m {
-		(*mf.m)[k] = v
+	isShowCaller := l.IsShowCaller
+	for k, v := range *l.MetaFields.Map() {
+		(*mf.Map())[k] = v
 	}
 	l.Mtx.RUnlock()
 
 	var hasLogId = false
+	var opts *Opts
 
 	for _, x := range *args {
 		if z, ok := x.(MetaFields); ok {
-			for k, v := range *z.m {
-				(*mf.m)[k] = v
+			for k, v := range *z.Map() {
+				(*mf.Map())[k] = v
 			}
 		} else if z, ok := x.(*MetaFields); ok {
-			for k, v := range *z.m {
-				(*mf.m)[k] = v
+			for k, v := range *z.Map() {
+				(*mf.Map())[k] = v
 			}
 		} else if z, ok := x.(*LogId); ok {
-			(*mf.m)["log_id"] = z.GetLogId(true)
+			(*mf.Map())["log_id"] = z.GetLogId(true)
 			hasLogId = true
 			// newArgs = append(newArgs, z.GetLogId(true))
 		} else if z, ok := x.(LogId); ok {
-			(*mf.m)["log_id"] = z.GetLogId(true)
+			(*mf.Map())["log_id"] = z.GetLogId(true)
 			// newArgs = append(newArgs, z.GetLogId(true))
 			hasLogId = true
+		} else if z, ok := x.(*ErrorId); ok {
+			if z != nil {
+				(*mf.Map())["error_id"] = z.Id
+			}
+		} else if z, ok := x.(ErrorId); ok {
+			(*mf.Map())["error_id"] = z.Id
+		} else if z, ok := x.(*Opts); ok {
+			if z != nil {
+				opts = shared.MergeOpts(opts, *z)
+			}
+		} else if z, ok := x.(Opts); ok {
+			opts = shared.MergeOpts(opts, z)
+		} else if err, ok := x.(error); ok {
+			// expanded into the cause chain, for both JSON and pretty output
+			if e := hlpr.NewErrorInfo(err); e != nil {
+				newArgs = append(newArgs, e)
+			} else {
+				newArgs = append(newArgs, nil)
+			}
 		} else {
 
 			if isLoggingJSON {
@@ -1451,155 +1299,88 @@ func (l *Logger) getMetaFields(args *[]interface{}) (*MetaFields, []interface{})
 		fmt.Println("missing log id:", string(debug.Stack()))
 	}
 
-	return mf, newArgs
-}
-
-func (l *Logger) Trace(args ...interface{}) {
-	if !l.IsLevelEnabled(ll.TRACE) {
-		return
+	if isShowCaller {
+		var skip = 0
+		if opts != nil {
+			skip = opts.SkipFrames
+		}
+		if c := hlpr.GetCaller(skip); c != nil {
+			(*mf.Map())["caller"] = c
+		}
 	}
-	t := time.Now()
-	n := shared.GetNextLogNum()
-	var meta, newArgs = l.getMetaFields(&args)
-	(*meta.m)["log_num"] = n
-	l.writeSwitch(t, ll.TRACE, meta, &newArgs)
-}
 
-func (l *Logger) Debug(args ...interface{}) {
-	if !l.IsLevelEnabled(ll.DEBUG) {
-		return
-	}
-	t := time.Now()
-	n := shared.GetNextLogNum()
-	var meta, newArgs = l.getMetaFields(&args)
-	(*meta.m)["log_num"] = n
-	l.writeSwitch(t, ll.DEBUG, meta, &newArgs)
+	return mf, newArgs, opts
 }
 
-func (l *Logger) Info(args ...interface{}) {
-	if !l.IsLevelEnabled(ll.INFO) {
+// Log writes a record at any level, including the ones added with ll.Register.
+func (l *Logger) Log(level ll.LogLevel, args ...interface{}) {
+	if !l.IsLevelEnabled(level) {
 		return
 	}
-	t := time.Now()
-	n := shared.GetNextLogNum()
-	var meta, newArgs = l.getMetaFields(&args)
-	(*meta.m)["log_num"] = n
-	l.writeSwitch(t, ll.INFO, meta, &newArgs)
+	l.logAt(time.Now(), level, args, 0)
 }
 
-func (l *Logger) Warn(args ...interface{}) {
-	if !l.IsLevelEnabled(ll.WARN) {
+// logAt is the pipeline of every record after the level check: sampling, meta fields, stack traces,
+// dedup and flushing. pc is the call site when the caller knows it (eg slog.Record.PC), else 0.
+func (l *Logger) logAt(t time.Time, level ll.LogLevel, args []interface{}, pc uintptr) {
+	if l.isSampledOut(level, sample.Template(args), shared.SkipFrames(args)) {
 		return
 	}
-	t := time.Now()
 	n := shared.GetNextLogNum()
-	var meta, newArgs = l.getMetaFields(&args)
-	(*meta.m)["log_num"] = n
-	l.writeSwitch(t, ll.WARN, meta, &newArgs)
+	var meta, newArgs, opts = l.getMetaFields(&args)
+	(*meta.Map())["log_num"] = n
+	if _, ok := (*meta.Map())["caller"]; ok && pc != 0 {
+		if c := hlpr.CallerFromPC(pc); c != nil {
+			(*meta.Map())["caller"] = c
+		}
+	}
+	newArgs = l.withStackTrace(level, newArgs, opts)
+	l.writeSwitch(t, level, meta, &newArgs)
+	l.flushAfter(level, opts)
 }
 
-func (l *Logger) Error(args ...interface{}) {
-	if !l.IsLevelEnabled(ll.ERROR) {
-		return
-	}
-	t := time.Now()
-	n := shared.GetNextLogNum()
-	var meta, newArgs = l.getMetaFields(&args)
-	(*meta.m)["log_num"] = n
-	filteredStackTrace := hlpr.GetFilteredStacktrace()
-	newArgs = append(newArgs, StackTrace{filteredStackTrace})
-	l.writeSwitch(t, ll.ERROR, meta, &newArgs)
+func (l *Logger) Trace(args ...interface{}) {
+	l.Log(ll.TRACE, args...)
 }
 
-func (l *Logger) Critical(args ...interface{}) {
-	if !l.IsLevelEnabled(ll.CRITICAL) {
-		return
-	}
-	t := time.Now()
-	n := shared.GetNextLogNum()
-	var meta, newArgs = l.getMetaFields(&args)
-	(*meta.m)["log_num"] = n
-	filteredStackTrace := hlpr.GetFilteredStacktrace()
-	newArgs = append(newArgs, StackTrace{filteredStackTrace})
-	l.writeSwitch(t, ll.CRITICAL, meta, &newArgs)
+func (l *Logger) Debug(args ...interface{}) {
+	l.Log(ll.DEBUG, args...)
 }
 
-type errorIdMarker struct{}
+func (l *Logger) Info(args ...interface{}) {
+	l.Log(ll.INFO, args...)
+}
 
-var eidMarker = &errorIdMarker{}
+func (l *Logger) Warn(args ...interface{}) {
+	l.Log(ll.WARN, args...)
+}
 
-type ErrorId struct {
-	Id            string
-	errorIdMarker *errorIdMarker
+func (l *Logger) Error(args ...interface{}) {
+	l.Log(ll.ERROR, args...)
 }
 
-type Opts struct {
-	IsPrintStackTrace bool
-	errorIdMarker     *errorIdMarker
+func (l *Logger) Critical(args ...interface{}) {
+	l.Log(ll.CRITICAL, args...)
 }
 
 func ErrId(id string) *ErrorId {
-	return &ErrorId{
-		id, eidMarker,
-	}
+	return shared.ErrId(id)
 }
 
 func ErrOpts(id string) *ErrorId {
-	return &ErrorId{
-		id, eidMarker,
-	}
-}
-
-type MF = map[string]interface{}
-
-// brand the below struct with unique ref
-type metaFieldsMarker struct{}
-
-var mfMarker = &metaFieldsMarker{}
-
-type MetaFields struct {
-	*metaFieldsMarker
-	UniqueMarker string
-	m            *MF
+	return shared.ErrOpts(id)
 }
 
 func MetaPairs(
 	k1 string, v1 interface{},
 	args ...interface{}) *MetaFields {
-	return MP(k1, v1, args...)
+	return shared.MP(k1, v1, args...)
 }
 
 func MP(
 	k1 string, v1 interface{},
 	args ...interface{}) *MetaFields {
-
-	m := make(map[string]interface{})
-	nargs := append([]interface{}{k1, v1}, args...) // prepend the first two arguments to new slice
-
-	currKey := ""
-
-	for i, a := range nargs {
-
-		if i%2 == 0 {
-			// operate on keys
-			v, ok := a.(string)
-			if ok {
-				currKey = v
-			} else {
-				panic("even arguments must be strings, odd arguments are interface{}")
-			}
-			if len(nargs) < i+2 {
-				panic("a key needs a respective value.")
-			}
-			continue
-		}
-
-		// operate on values
-		m[currKey] = a
-	}
-
-	return NewMetaFields(&m)
-
+	return shared.MP(k1, v1, args...)
 }
 
 func (l *Logger) TagPair(k string, v interface{}) *Logger {
@@ -1607,88 +1388,61 @@ func (l *Logger) TagPair(k string, v interface{}) *Logger {
 	return l.Child(&z)
 }
 
+// Named is a child logger with the name appended to this one's, eg db => db.pool,
+// the records get the full name under "logger".
+func (l *Logger) Named(name string) *Logger {
+	l.Mtx.RLock()
+	var fullName = shared.JoinName(l.Name, name)
+	l.Mtx.RUnlock()
+	var z = l.Child(&map[string]interface{}{"logger": fullName})
+	z.Name = fullName
+	return z
+}
+
 func (l *Logger) Tags(z *map[string]interface{}) *Logger {
 	return l.Create(z)
 }
 
-func (l *Logger) TraceF(s string, args ...interface{}) {
-	if !l.IsLevelEnabled(ll.TRACE) {
+// LogF is Log with a format string.
+func (l *Logger) LogF(level ll.LogLevel, s string, args ...interface{}) {
+	if !l.IsLevelEnabled(level) {
+		return
+	}
+	if l.isSampledOut(level, s, 0) {
 		return
 	}
 	t := time.Now()
 	n := shared.GetNextLogNum()
 	var empty []interface{}
-	var meta, _ = l.getMetaFields(&empty)
-	(*meta.m)["log_num"] = n
-	l.writeSwitch(t, ll.TRACE, meta, &[]interface{}{fmt.Sprintf(s, args...)})
+	var meta, _, _ = l.getMetaFields(&empty)
+	(*meta.Map())["log_num"] = n
+	var newArgs = l.withStackTrace(level, []interface{}{fmt.Sprintf(s, args...)}, nil)
+	l.writeSwitch(t, level, meta, &newArgs)
+	l.flushAfter(level, nil)
+}
+
+func (l *Logger) TraceF(s string, args ...interface{}) {
+	l.LogF(ll.TRACE, s, args...)
 }
 
 func (l *Logger) DebugF(s string, args ...interface{}) {
-	if !l.IsLevelEnabled(ll.DEBUG) {
-		return
-	}
-	t := time.Now()
-	n := shared.GetNextLogNum()
-	var empty []interface{}
-	var meta, _ = l.getMetaFields(&empty)
-	(*meta.m)["log_num"] = n
-	l.writeSwitch(t, ll.DEBUG, meta, &[]interface{}{fmt.Sprintf(s, args...)})
+	l.LogF(ll.DEBUG, s, args...)
 }
 
 func (l *Logger) InfoF(s string, args ...interface{}) {
-	if !l.IsLevelEnabled(ll.INFO) {
-		return
-	}
-	t := time.Now()
-	n := shared.GetNextLogNum()
-	var empty []interface{}
-	var meta, _ = l.getMetaFields(&empty)
-	(*meta.m)["log_num"] = n
-	l.writeSwitch(t, ll.INFO, meta, &[]interface{}{fmt.Sprintf(s, args...)})
+	l.LogF(ll.INFO, s, args...)
 }
 
 func (l *Logger) WarnF(s string, args ...interface{}) {
-	if !l.IsLevelEnabled(ll.WARN) {
-		return
-	}
-	t := time.Now()
-	n := shared.GetNextLogNum()
-	var empty []interface{}
-	var meta, _ = l.getMetaFields(&empty)
-	(*meta.m)["log_num"] = n
-	l.writeSwitch(t, ll.WARN, meta, &[]interface{}{fmt.Sprintf(s, args...)})
-}
-
-type StackTrace struct {
-	ErrorTrace *[]string
+	l.LogF(ll.WARN, s, args...)
 }
 
 func (l *Logger) ErrorF(s string, args ...interface{}) {
-	if !l.IsLevelEnabled(ll.ERROR) {
-		return
-	}
-	t := time.Now()
-	n := shared.GetNextLogNum()
-	filteredStackTrace := hlpr.GetFilteredStacktrace()
-	formattedString := fmt.Sprintf(s, args...)
-	var empty []interface{}
-	var meta, _ = l.getMetaFields(&empty)
-	(*meta.m)["log_num"] = n
-	l.writeSwitch(t, ll.ERROR, meta, &[]interface{}{formattedString, StackTrace{filteredStackTrace}})
+	l.LogF(ll.ERROR, s, args...)
 }
 
 func (l *Logger) CriticalF(s string, args ...interface{}) {
-	if !l.IsLevelEnabled(ll.CRITICAL) {
-		return
-	}
-	t := time.Now()
-	n := shared.GetNextLogNum()
-	filteredStackTrace := hlpr.GetFilteredStacktrace()
-	formattedString := fmt.Sprintf(s, args...)
-	var empty []interface{}
-	var meta, _ = l.getMetaFields(&empty)
-	(*meta.m)["log_num"] = n
-	l.writeSwitch(t, ll.CRITICAL, meta, &[]interface{}{formattedString, StackTrace{filteredStackTrace}})
+	l.LogF(ll.CRITICAL, s, args...)
 }
 
 func (l *Logger) NewLine() {
@@ -1737,4 +1491,6 @@ func init() {
 
 	// log.SetFlags(log.LstdFlags | log.Llongfile)
 
+	// it writes to stdout, but lives as long as the process, so it is listed by the admin handler and signals.Dump
+	shared.RegisterLogger(DefaultLogger)
 }
@@ -2,13 +2,26 @@ package lib
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"log/slog"
 	"os"
+	"regexp"
 	"strings"
 	"testing"
+	"time"
 
+	jctx "github.com/oresoftware/json-logging/jlog/ctx"
+	hlpr "github.com/oresoftware/json-logging/jlog/helper"
 	ll "github.com/oresoftware/json-logging/jlog/level"
+	"github.com/oresoftware/json-logging/jlog/mult"
+	"github.com/oresoftware/json-logging/jlog/sample"
+	"github.com/oresoftware/json-logging/jlog/shared"
+	"github.com/oresoftware/json-logging/jlog/writer"
+	"github.com/oresoftware/json-logging/test/callsite"
 )
 
 func decodeJSONLines(t *testing.T, raw []byte) [][]interface{} {
@@ -205,3 +218,803 @@ func TestJSONCircularFallbackStaysValid(t *testing.T) {
 		t.Fatalf("expected circular reference marker in output: %s", string(raw))
 	}
 }
+
+func TestSharedMetaTypesAndLoggerInterface(t *testing.T) {
+	f := tempLogFile(t)
+
+	var log shared.Logger = CreateLogger("shared-iface").
+		SetOutputFile(f).
+		SetToJSONOutput().
+		SetLogLevel(ll.TRACE)
+
+	// meta built via the mult package should be recognized by lib
+	child := log.(shared.ChildMaker).TagPairLogger("component", "db")
+	child.Info(mult.MP("requestId", "req-2"), mult.Id("shared-log-id"), "hello")
+
+	records := decodeJSONLines(t, readLogFile(t, f))
+	if len(records) != 1 {
+		t.Fatalf("expected one record, got %d", len(records))
+	}
+
+	meta := records[0][6].(map[string]interface{})
+	if meta["requestId"] != "req-2" || meta["component"] != "db" || meta["log_id"] != "shared-log-id" {
+		t.Fatalf("unexpected metadata: %#v", meta)
+	}
+	if messages := records[0][7].([]interface{}); len(messages) != 1 || messages[0] != "hello" {
+		t.Fatalf("unexpected messages: %#v", messages)
+	}
+}
+
+func TestSlogHandlerWritesBunionRecords(t *testing.T) {
+	f := tempLogFile(t)
+
+	log := CreateLogger("slog-json").
+		SetOutputFile(f).
+		SetToJSONOutput().
+		SetLogLevel(ll.INFO)
+
+	sl := log.Slog().With("requestId", "req-3").WithGroup("http")
+	sl.Debug("hidden")
+	// a dangling value becomes a plain arg rather than an attr
+	kvs := []interface{}{"status", 200, slog.Group("user", "id", 7), "extra"}
+	sl.Warn("slow request", kvs...)
+
+	records := decodeJSONLines(t, readLogFile(t, f))
+	if len(records) != 1 {
+		t.Fatalf("expected one record, got %d", len(records))
+	}
+	if records[0][2] != "WARN" {
+		t.Fatalf("unexpected level: %#v", records[0][2])
+	}
+
+	meta := records[0][6].(map[string]interface{})
+	if meta["requestId"] != "req-3" || meta["http.status"] != float64(200) || meta["http.user.id"] != float64(7) {
+		t.Fatalf("unexpected metadata: %#v", meta)
+	}
+
+	messages := records[0][7].([]interface{})
+	if len(messages) != 2 || messages[0] != "slow request" || messages[1] != "extra" {
+		t.Fatalf("unexpected messages: %#v", messages)
+	}
+}
+
+func TestSlogHandlerUsesTheLogPipeline(t *testing.T) {
+	var buf bytes.Buffer
+	log := CreateLogger("slog-pipeline").SetOutput(&buf).SetToJSONOutput().SetShowCaller(true)
+
+	log.Slog().Error("failed", "err", errors.New("boom"), slog.Group("db", "err", errors.New("gone")))
+
+	records := decodeJSONLines(t, buf.Bytes())
+	if len(records) != 1 {
+		t.Fatalf("expected one record, got %d", len(records))
+	}
+
+	meta := records[0][6].(map[string]interface{})
+	for key, msg := range map[string]string{"err": "boom", "db.err": "gone"} {
+		e, ok := meta[key].([]interface{})
+		if !ok || e[0] != hlpr.ErrorMarker || e[2] != msg {
+			t.Fatalf("expected %s to be written as an error: %#v", key, meta[key])
+		}
+	}
+
+	// the call site comes from the record's PC, which is this test, not a frame outside of json-logging
+	caller := meta["caller"].(map[string]interface{})
+	if !strings.HasSuffix(caller["file"].(string), "lib_test.go") {
+		t.Fatalf("unexpected caller: %#v", caller)
+	}
+
+	// ERROR is the default StackTraceLevel
+	args := records[0][7].([]interface{})
+	if _, ok := args[len(args)-1].(map[string]interface{})["Frames"]; len(args) != 2 || !ok {
+		t.Fatalf("expected a stack trace arg: %#v", args)
+	}
+}
+
+func TestSlogHandlerOmitsZeroTime(t *testing.T) {
+	var buf bytes.Buffer
+	log := CreateLogger("slog-zero-time").SetOutput(&buf).SetToJSONOutput()
+
+	// slog.Handler says a zero time must be ignored, not replaced
+	if err := NewSlogHandler(log).Handle(context.Background(), slog.NewRecord(time.Time{}, slog.LevelInfo, "no time", 0)); err != nil {
+		t.Fatal(err)
+	}
+
+	records := decodeJSONLines(t, buf.Bytes())
+	if len(records) != 1 || records[0][5] != "" {
+		t.Fatalf("expected an empty date, got %#v", records)
+	}
+}
+
+func TestCtxMethodsExtractContextFields(t *testing.T) {
+	f := tempLogFile(t)
+
+	log := CreateLogger("ctx-json").
+		SetOutputFile(f).
+		SetToJSONOutput().
+		SetLogLevel(ll.TRACE)
+
+	ctx := jctx.WithRequestId(context.Background(), "req-4")
+	ctx = jctx.WithMetaPairs(ctx, "tenant", "acme")
+	ctx = log.TagPair("component", "http").WithContext(ctx)
+
+	FromContext(ctx).InfoCtx(ctx, MP("tenant", "override"), "handled")
+
+	records := decodeJSONLines(t, readLogFile(t, f))
+	if len(records) != 1 {
+		t.Fatalf("expected one record, got %d", len(records))
+	}
+
+	meta := records[0][6].(map[string]interface{})
+	if meta["request_id"] != "req-4" || meta["component"] != "http" || meta["tenant"] != "override" {
+		t.Fatalf("unexpected metadata: %#v", meta)
+	}
+
+	if FromContext(context.Background()) != DefaultLogger {
+		t.Fatal("expected DefaultLogger when the context has no logger")
+	}
+}
+
+func TestSetOutputAcceptsAnyWriter(t *testing.T) {
+	var buf bytes.Buffer
+
+	log := CreateLogger("buffer-json").
+		SetOutput(&buf).
+		SetToJSONOutput().
+		SetLogLevel(ll.TRACE)
+
+	log.Info("to a buffer")
+	log.Child(&map[string]interface{}{"child": true}).Warn("from a child")
+
+	records := decodeJSONLines(t, buf.Bytes())
+	if len(records) != 2 {
+		t.Fatalf("expected two records in the buffer, got %d", len(records))
+	}
+	if records[1][2] != "WARN" || records[1][6].(map[string]interface{})["child"] != true {
+		t.Fatalf("unexpected child record: %#v", records[1])
+	}
+}
+
+func TestAsyncModeWritesAllRecords(t *testing.T) {
+	f := tempLogFile(t)
+
+	log := CreateLogger("async-json").
+		SetOutputFile(f).
+		SetToJSONOutput().
+		SetLogLevel(ll.TRACE).
+		SetAsync(writer.AsyncParams{Capacity: 16, BatchSize: 4})
+
+	for i := 0; i < 50; i++ {
+		log.Info("async", i)
+	}
+
+	aw := log.outputFile().(*writer.AsyncWriter)
+	if err := aw.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	records := decodeJSONLines(t, readLogFile(t, f))
+	if len(records) != 50 {
+		t.Fatalf("expected 50 records, got %d", len(records))
+	}
+	for i, r := range records {
+		if messages := r[7].([]interface{}); messages[1] != float64(i) {
+			t.Fatalf("records out of order at %d: %#v", i, messages)
+		}
+	}
+	if s := log.AsyncStats(); s.Written != 50 || s.Dropped != 0 {
+		t.Fatalf("unexpected async stats: %+v", s)
+	}
+}
+
+func TestShowCallerRecordsTheCallSite(t *testing.T) {
+	var buf bytes.Buffer
+
+	log := CreateLogger("caller-json").
+		SetOutput(&buf).
+		SetToJSONOutput().
+		SetLogLevel(ll.TRACE).
+		SetShowCaller(true)
+
+	callsite.Call(func() { log.Info("here") })
+	callsite.Call(func() { log.InfoF("and %s", "here") })
+	callsite.Call(func() { log.SetShowCaller(false).Info("not here") })
+
+	records := decodeJSONLines(t, buf.Bytes())
+	if len(records) != 3 {
+		t.Fatalf("expected three records, got %d", len(records))
+	}
+
+	for _, r := range records[:2] {
+		caller, ok := r[6].(map[string]interface{})["caller"].(map[string]interface{})
+		if !ok {
+			t.Fatalf("expected a caller in the meta: %#v", r[6])
+		}
+		if !strings.HasSuffix(caller["file"].(string), "callsite.go") || caller["line"].(float64) < 1 ||
+			caller["func"] != "github.com/oresoftware/json-logging/test/callsite.Call" {
+			t.Fatalf("unexpected caller: %#v", caller)
+		}
+	}
+
+	if _, ok := records[2][6].(map[string]interface{})["caller"]; ok {
+		t.Fatalf("expected no caller: %#v", records[2][6])
+	}
+
+	buf.Reset()
+	pretty := NewLogger(LoggerParams{AppName: "caller-pretty", Output: &buf, IsShowCaller: true})
+	pretty.IsLoggingJSON = false
+	callsite.Call(func() { pretty.Warn("pretty") })
+	if !strings.Contains(buf.String(), "callsite/callsite.go:") {
+		t.Fatalf("expected file:line in the pretty output: %q", buf.String())
+	}
+}
+
+func TestStackTracesAreStructuredAndConfigurable(t *testing.T) {
+	var buf bytes.Buffer
+
+	log := CreateLogger("stack-json").
+		SetOutput(&buf).
+		SetToJSONOutput().
+		SetLogLevel(ll.TRACE)
+
+	frames := func(r []interface{}) []interface{} {
+		args := r[7].([]interface{})
+		st, ok := args[len(args)-1].(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		f, _ := st["Frames"].([]interface{})
+		return f
+	}
+
+	callsite.Call(func() {
+		log.Warn("no stack by default")
+		log.ErrorF("stack by %s", "default")
+		log.SetStackTraceLevel(ll.WARN).Warn("stack on warn")
+		log.SetStackParams(StackParams{MaxDepth: 1}).Error("one frame")
+		log.SetStackParams(StackParams{Exclude: []string{"github.com/oresoftware/json-logging/test/..."}}).Error("excluded")
+		log.SetStackParams(StackParams{Include: []string{"testing"}}).Error("included")
+		log.SetStackTraceLevel(ll.CRITICAL + 1).Critical("no stacks at all")
+	})
+
+	records := decodeJSONLines(t, buf.Bytes())
+	if len(records) != 7 {
+		t.Fatalf("expected seven records, got %d", len(records))
+	}
+
+	if frames(records[0]) != nil || frames(records[6]) != nil {
+		t.Fatalf("expected no stack: %#v %#v", records[0][7], records[6][7])
+	}
+
+	for _, r := range records[1:4] {
+		fs := frames(r)
+		if len(fs) < 1 {
+			t.Fatalf("expected a stack: %#v", r[7])
+		}
+		top := fs[0].(map[string]interface{})
+		if top["function"] != "github.com/oresoftware/json-logging/test/callsite.Call" ||
+			top["package"] != "github.com/oresoftware/json-logging/test/callsite" || top["line"].(float64) < 1 {
+			t.Fatalf("unexpected top frame: %#v", top)
+		}
+	}
+
+	if len(frames(records[3])) != 1 {
+		t.Fatalf("expected one frame, got %#v", frames(records[3]))
+	}
+
+	for _, r := range records[4:6] {
+		fs := frames(r)
+		if len(fs) < 1 {
+			t.Fatalf("expected a stack: %#v", r[7])
+		}
+		for _, f := range fs {
+			if f.(map[string]interface{})["package"] != "testing" {
+				t.Fatalf("unexpected frame: %#v", f)
+			}
+		}
+	}
+}
+
+func TestStackTraceLevelDefaultsToErrorWhenUnset(t *testing.T) {
+	var buf bytes.Buffer
+
+	hasStack := func(r []interface{}) bool {
+		args := r[7].([]interface{})
+		st, ok := args[len(args)-1].(map[string]interface{})
+		return ok && st["Frames"] != nil
+	}
+
+	// built without a constructor, StackTraceLevel is the zero value
+	literal := &Logger{AppName: "literal", Output: &buf, IsLoggingJSON: true, ForceJSON: true, MetaFields: NewMetaFields(&MF{})}
+	literal.Warn("no stack")
+	literal.Error("stack")
+	literal.SetStackTraceLevel(ll.TRACE).Info("stack on every record once set")
+
+	fromParams := NewLogger(LoggerParams{AppName: "params", Output: &buf, ForceJSON: true, StackTraceLevel: ll.WARN})
+	fromParams.Info("no stack")
+	fromParams.Warn("stack")
+	fromParams.Child(&MF{}).Warn("the child keeps it")
+
+	var got []bool
+	for _, r := range decodeJSONLines(t, buf.Bytes()) {
+		got = append(got, hasStack(r))
+	}
+	if fmt.Sprint(got) != "[false true true false true true]" {
+		t.Fatalf("unexpected stacks: %v", got)
+	}
+}
+
+func TestErrorsAreExpandedIntoTheirCauseChain(t *testing.T) {
+	var buf bytes.Buffer
+
+	log := CreateLogger("errors-json").
+		SetOutput(&buf).
+		SetToJSONOutput().
+		SetLogLevel(ll.TRACE)
+
+	_, statErr := os.Stat("/does/not/exist")
+	joined := errors.Join(errors.New("first"), fmt.Errorf("second: %w", statErr))
+	log.Warn("failed", fmt.Errorf("request: %w", joined))
+
+	records := decodeJSONLines(t, buf.Bytes())
+	// ["@error", type, message, fields, causes]
+	e := records[0][7].([]interface{})[1].([]interface{})
+	causes := func(a []interface{}, i int) []interface{} {
+		return a[4].([]interface{})[i].([]interface{})
+	}
+
+	if e[0] != "@error" || e[1] != "*fmt.wrapError" || !strings.HasPrefix(e[2].(string), "request: first") {
+		t.Fatalf("unexpected error: %#v", e)
+	}
+
+	join := causes(e, 0)
+	if join[1] != "*errors.joinError" || len(join[4].([]interface{})) != 2 {
+		t.Fatalf("unexpected joined error: %#v", join)
+	}
+
+	pathErr := causes(causes(join, 1), 0)
+	if pathErr[1] != "*fs.PathError" || pathErr[3].(map[string]interface{})["Path"] != "/does/not/exist" {
+		t.Fatalf("unexpected path error: %#v", pathErr)
+	}
+	if errno := causes(pathErr, 0); errno[1] != "syscall.Errno" || len(errno[4].([]interface{})) != 0 {
+		t.Fatalf("unexpected errno: %#v", errno)
+	}
+
+	// the decoded array reads back into the same chain
+	back, ok := hlpr.ErrorFromArg(records[0][7].([]interface{})[1])
+	if !ok || back.Causes[0].Causes[1].Causes[0].Fields["Path"] != "/does/not/exist" {
+		t.Fatalf("expected the error to be read back: %#v", back)
+	}
+	if _, ok := hlpr.ErrorFromArg(map[string]interface{}{"type": "x", "message": "y"}); ok {
+		t.Fatal("expected a map to not be taken for an error")
+	}
+
+	buf.Reset()
+	pretty := NewLogger(LoggerParams{AppName: "errors-pretty", Output: &buf})
+	pretty.IsLoggingJSON = false
+	pretty.Warn(fmt.Errorf("request: %w", joined))
+
+	out := regexp.MustCompile(`\x1b\[[0-9;]*m`).ReplaceAllString(buf.String(), "")
+	for _, s := range []string{"request: first; second:", "\n    caused by: ", "\n        joined: first", "Path=/does/not/exist", "(syscall.Errno)"} {
+		if !strings.Contains(out, s) {
+			t.Fatalf("expected %q in the pretty output: %q", s, out)
+		}
+	}
+}
+
+// logs on behalf of its caller, so it skips its own frame
+func TestErrorIdAndOptsChangeTheCall(t *testing.T) {
+	var buf bytes.Buffer
+
+	log := CreateLogger("opts-json").
+		SetOutput(&buf).
+		SetToJSONOutput().
+		SetLogLevel(ll.TRACE).
+		SetShowCaller(true)
+
+	log.Info("with error id", ErrId("E123"), &Opts{IsPrintStackTrace: true})
+	log.Error("no stack", Opts{IsSkipStackTrace: true})
+	callsite.Helper(func() { log.Warn("helper", Opts{IsPrintStackTrace: true, SkipFrames: 1}) })
+
+	records := decodeJSONLines(t, buf.Bytes())
+	if len(records) != 3 {
+		t.Fatalf("expected three records, got %d", len(records))
+	}
+
+	for _, r := range records {
+		for _, a := range r[7].([]interface{}) {
+			if m, ok := a.(map[string]interface{}); ok && m["Frames"] == nil {
+				t.Fatalf("expected ErrorId/Opts to be left out of the args: %#v", r[7])
+			}
+		}
+	}
+
+	if records[0][6].(map[string]interface{})["error_id"] != "E123" {
+		t.Fatalf("expected an error_id in the meta: %#v", records[0][6])
+	}
+	if args := records[0][7].([]interface{}); len(args) != 2 {
+		t.Fatalf("expected a forced stack trace: %#v", args)
+	}
+	if args := records[1][7].([]interface{}); len(args) != 1 {
+		t.Fatalf("expected no stack trace: %#v", args)
+	}
+
+	args := records[2][7].([]interface{})
+	if len(args) != 2 {
+		t.Fatalf("expected the helper's stack trace: %#v", args)
+	}
+	top := args[1].(map[string]interface{})["Frames"].([]interface{})[0].(map[string]interface{})
+	caller := records[2][6].(map[string]interface{})["caller"].(map[string]interface{})
+	for _, fn := range []interface{}{top["function"], caller["func"]} {
+		if fn != "github.com/oresoftware/json-logging/test/callsite.Helper" {
+			t.Fatalf("expected the helper's frame to be skipped: %#v %#v", top, caller)
+		}
+	}
+}
+
+func TestMustFlushOptsFlushesTheOutput(t *testing.T) {
+	f := tempLogFile(t)
+
+	log := CreateLogger("must-flush").
+		SetOutputFile(f).
+		SetToJSONOutput().
+		SetAsync(writer.AsyncParams{Capacity: 16, BatchSize: 16})
+
+	log.Info("queued")
+	log.Info("flushed", Opts{IsMustFlush: true})
+
+	if records := decodeJSONLines(t, readLogFile(t, f)); len(records) != 2 {
+		t.Fatalf("expected both records after the must-flush, got %d", len(records))
+	}
+}
+
+type panicValue struct {
+	Code   int
+	secret string
+}
+
+func TestRecoverLogsPanicsAtCritical(t *testing.T) {
+	var buf bytes.Buffer
+
+	log := CreateLogger("recover-json").
+		SetOutput(&buf).
+		SetToJSONOutput().
+		SetStackTraceLevel(ll.CRITICAL + 1)
+
+	func() {
+		defer log.Recover()
+		callsite.Panic(panicValue{Code: 7, secret: "x"})
+	}()
+
+	done := make(chan struct{})
+	log.Go(func() {
+		defer close(done)
+		callsite.Panic(errors.New("in a goroutine"))
+	})
+	<-done
+
+	rePanicked := func() (r interface{}) {
+		defer func() { r = recover() }()
+		defer log.SetPanicParams(PanicParams{IsRePanic: true}).Recover()
+		callsite.Panic("again")
+		return nil
+	}()
+	if rePanicked != "again" {
+		t.Fatalf("expected the panic to be rethrown, got %#v", rePanicked)
+	}
+
+	var code = 0
+	exit = func(c int) { code = c }
+	defer func() { exit = os.Exit }()
+	func() {
+		defer log.SetPanicParams(PanicParams{IsRePanic: true, ExitCode: 3}).Recover()
+		callsite.Panic("exit")
+	}()
+	if code != 3 {
+		t.Fatalf("expected exit code 3, got %d", code)
+	}
+
+	records := decodeJSONLines(t, buf.Bytes())
+	if len(records) != 4 {
+		t.Fatalf("expected four records, got %d", len(records))
+	}
+
+	for _, r := range records {
+		if r[2] != "CRITICAL" || r[6].(map[string]interface{})["panic"] != true {
+			t.Fatalf("unexpected panic record: %#v", r)
+		}
+		args := r[7].([]interface{})
+		frames := args[len(args)-1].(map[string]interface{})["Frames"].([]interface{})
+		if fn := frames[0].(map[string]interface{})["function"].(string); fn != "github.com/oresoftware/json-logging/test/callsite.Panic" {
+			t.Fatalf("expected the stack to start where the panic happened: %#v", frames)
+		}
+	}
+
+	if v := records[0][7].([]interface{})[1]; !strings.Contains(fmt.Sprint(v), "secret") {
+		t.Fatalf("expected the panic value to be inspected: %#v", v)
+	}
+	if v := records[1][7].([]interface{})[1].([]interface{}); v[2] != "in a goroutine" {
+		t.Fatalf("expected the error to be expanded: %#v", v)
+	}
+}
+
+func TestPanicRecordsAreNotSampledOrDeduped(t *testing.T) {
+	var buf bytes.Buffer
+	log := CreateLogger("recover-sampled").SetOutput(&buf).SetToJSONOutput().
+		SetSampling(SampleParams{Levels: map[ll.LogLevel]SampleRule{ll.CRITICAL: {First: 1}}, KeyBy: sample.KeyByLevel}).
+		SetDedupWindow(time.Minute)
+
+	log.Critical("uses up the sampling rule")
+	for i := 0; i < 2; i++ {
+		func() {
+			defer log.Recover()
+			callsite.Panic("the same panic")
+		}()
+	}
+
+	var panics = 0
+	for _, r := range decodeJSONLines(t, buf.Bytes()) {
+		if r[6].(map[string]interface{})["panic"] == true {
+			panics++
+		}
+	}
+	if panics != 2 {
+		t.Fatalf("expected both panics to be logged, got %d", panics)
+	}
+}
+
+func TestSamplingDropsRecordsAndLogsASummary(t *testing.T) {
+	var buf bytes.Buffer
+
+	log := CreateLogger("sample-json").
+		SetOutput(&buf).
+		SetToJSONOutput().
+		SetLogLevel(ll.TRACE).
+		SetSampling(SampleParams{
+			Levels: map[ll.LogLevel]SampleRule{ll.DEBUG: {First: 2, Thereafter: 5}},
+			KeyBy:  sample.KeyByMessage,
+		})
+
+	for i := 0; i < 12; i++ {
+		log.Debug("hot path", i)
+		log.DebugF("hot %s", "format")
+	}
+	log.Info("not sampled")
+	log.Child(&map[string]interface{}{"child": true}).Debug("hot path")
+
+	if err := log.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	records := decodeJSONLines(t, buf.Bytes())
+	// 2 + every 5th of the other 10, for both keys, the info, and a summary for the logger and one for the child
+	if len(records) != 4+4+1+2 {
+		t.Fatalf("expected eleven records, got %d", len(records))
+	}
+
+	summary := records[len(records)-2]
+	sampled, ok := summary[6].(map[string]interface{})["sampled"].(map[string]interface{})
+	if !ok || summary[2] != "DEBUG" || sampled["suppressed"] != float64(8+8) || len(sampled["keys"].([]interface{})) != 2 {
+		t.Fatalf("unexpected summary: %#v", summary)
+	}
+
+	// the child's summary is written through the child, with its meta fields
+	summary = records[len(records)-1]
+	sampled, ok = summary[6].(map[string]interface{})["sampled"].(map[string]interface{})
+	if !ok || summary[6].(map[string]interface{})["child"] != true || sampled["suppressed"] != float64(1) {
+		t.Fatalf("unexpected child summary: %#v", summary)
+	}
+
+	buf.Reset()
+	log.SetSampling(SampleParams{}).Debug("off")
+	if records := decodeJSONLines(t, buf.Bytes()); len(records) != 1 {
+		t.Fatalf("expected sampling to be off, got %d records", len(records))
+	}
+}
+
+func TestDedupSwallowsRepeatedRecords(t *testing.T) {
+	var buf bytes.Buffer
+
+	log := CreateLogger("dedup-json").
+		SetOutput(&buf).
+		SetToJSONOutput().
+		SetDedupWindow(time.Minute)
+
+	for i := 0; i < 5; i++ {
+		log.Warn("retrying", "db")
+	}
+	log.Warn("giving up")
+
+	records := decodeJSONLines(t, buf.Bytes())
+	if len(records) != 3 {
+		t.Fatalf("expected three records, got %d", len(records))
+	}
+
+	repeat := records[1]
+	if repeat[2] != "WARN" || !strings.HasPrefix(repeat[7].([]interface{})[0].(string), "previous message repeated 4 times over ") {
+		t.Fatalf("unexpected follow-up record: %#v", repeat)
+	}
+	if r := repeat[6].(map[string]interface{})["repeated"].(map[string]interface{}); r["count"] != float64(4) {
+		t.Fatalf("unexpected repeated meta: %#v", r)
+	}
+
+	buf.Reset()
+	pretty := NewLogger(LoggerParams{AppName: "dedup-pretty", Output: &buf}).SetDedupWindow(time.Minute)
+	pretty.IsLoggingJSON = false
+	pretty.Info("same")
+	pretty.Info("same")
+	pretty.Flush(context.Background())
+
+	if out := buf.String(); strings.Count(out, "same") != 1 || !strings.Contains(out, "previous message repeated 1 time over") {
+		t.Fatalf("unexpected pretty output: %q", out)
+	}
+}
+
+func TestCustomLevels(t *testing.T) {
+	notice, err := ll.Register(ll.LevelInfo{Severity: 35, Name: "NOTICE", Style: func(s string) string { return "<" + s + ">" }})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	log := CreateLogger("custom-levels").SetOutput(&buf).SetToJSONOutput().SetLogLevel(notice)
+
+	log.Info("hidden")
+	log.Log(notice, "shown")
+	log.LogF(notice, "shown %d", 2)
+	log.Warn("also shown")
+
+	records := decodeJSONLines(t, buf.Bytes())
+	if len(records) != 3 || records[0][2] != "NOTICE" || records[1][2] != "NOTICE" || records[2][2] != "WARN" {
+		t.Fatalf("unexpected records: %#v", records)
+	}
+	if level := shared.ToLogLevel("notice"); level != notice {
+		t.Fatalf("could not parse the custom level: %v", level)
+	}
+
+	buf.Reset()
+	pretty := NewLogger(LoggerParams{AppName: "custom-pretty", Output: &buf})
+	pretty.IsLoggingJSON = false
+	pretty.Log(notice, "hello")
+	if !strings.Contains(buf.String(), "<NOTICE>") {
+		t.Fatalf("the style of the custom level was not used: %q", buf.String())
+	}
+}
+
+func TestNamedLoggerLevels(t *testing.T) {
+	SetNamedLevel("test-db", ll.DEBUG)
+	SetNamedLevel("test-db.pool", ll.WARN)
+	defer ClearNamedLevel("test-db")
+	defer ClearNamedLevel("test-db.pool")
+
+	var buf bytes.Buffer
+	root := CreateLogger("named").SetOutput(&buf).SetToJSONOutput().SetLogLevel(ll.INFO)
+	db := root.Named("test-db")
+	pool := db.Named("pool")
+	conn := pool.Named("conn")
+
+	root.Debug("root debug")
+	db.Debug("db debug")
+	pool.Info("pool info")
+	conn.Warn("conn warn")
+
+	records := decodeJSONLines(t, buf.Bytes())
+	if len(records) != 2 {
+		t.Fatalf("expected two records, got %#v", records)
+	}
+	if records[0][6].(map[string]interface{})["logger"] != "test-db" || records[1][6].(map[string]interface{})["logger"] != "test-db.pool.conn" {
+		t.Fatalf("unexpected logger names: %#v", records)
+	}
+	if conn.Name != "test-db.pool.conn" || conn.EffectiveLevel() != ll.WARN || root.EffectiveLevel() != ll.INFO {
+		t.Fatalf("unexpected levels: %s %v %v", conn.Name, conn.EffectiveLevel(), root.EffectiveLevel())
+	}
+
+	ClearNamedLevel("test-db.pool")
+	if !pool.IsDebugEnabled() {
+		t.Fatal("test-db.pool should inherit DEBUG from test-db")
+	}
+}
+
+func TestChildLoggersFollowTheLevelOfTheirParent(t *testing.T) {
+	var buf bytes.Buffer
+	root := CreateLogger("child-levels").SetOutput(&buf).SetToJSONOutput().SetLogLevel(ll.WARN)
+	child := root.Child(&map[string]interface{}{"child": true})
+	named := child.Named("worker")
+
+	root.SetLogLevel(ll.DEBUG)
+	if !child.IsDebugEnabled() || !named.IsDebugEnabled() || named.Level() != ll.DEBUG {
+		t.Fatalf("expected the children to follow the root: %v %v", child.Level(), named.Level())
+	}
+
+	// a level of its own, for the child and the ones below it, the root is not changed
+	child.SetLogLevel(ll.ERROR)
+	root.SetLogLevel(ll.TRACE)
+	if child.Level() != ll.ERROR || named.Level() != ll.ERROR || root.Level() != ll.TRACE {
+		t.Fatalf("unexpected levels: %v %v %v", child.Level(), named.Level(), root.Level())
+	}
+
+	var zero = CreateLogger("zero-level")
+	if zero.Describe().Level != "TRACE" {
+		t.Fatalf("expected the zero value to be TRACE: %s", zero.Describe().Level)
+	}
+}
+
+func TestParseNamedLevels(t *testing.T) {
+	levels, err := shared.ParseNamedLevels("db=DEBUG, http.client=warn,,oops,x=LOUD")
+	if err == nil || !strings.Contains(err.Error(), "oops") || !strings.Contains(err.Error(), "x=LOUD") {
+		t.Fatalf("expected an error about the invalid pairs, got %v", err)
+	}
+	if len(levels) != 2 || levels["db"] != ll.DEBUG || levels["http.client"] != ll.WARN {
+		t.Fatalf("unexpected levels: %v", levels)
+	}
+}
+
+func TestSetOutputUsesJSONForOtherWriters(t *testing.T) {
+	var buf bytes.Buffer
+	log := NewLogger(LoggerParams{AppName: "output-json"})
+	log.IsLoggingJSON = false
+	log.SetOutput(&buf).Info("hello")
+	if records := decodeJSONLines(t, buf.Bytes()); len(records) != 1 {
+		t.Fatalf("expected a JSON record, got %q", buf.String())
+	}
+}
+
+func TestOnlyLoggersWhichOwnAnOutputAreRegistered(t *testing.T) {
+	isRegistered := func(l shared.Logger) bool {
+		for _, z := range shared.RegisteredLoggers() {
+			if z == l {
+				return true
+			}
+		}
+		return false
+	}
+
+	var stdout = CreateLogger("registry-stdout")
+	if isRegistered(stdout) {
+		t.Fatal("expected a stdout logger not to be registered")
+	}
+	if !isRegistered(DefaultLogger) {
+		t.Fatal("expected the default logger to be registered")
+	}
+
+	var owner = CreateLogger("registry-owner").SetOutput(io.Discard).SetOutput(io.Discard)
+	var count = 0
+	for _, z := range shared.RegisteredLoggers() {
+		if z == owner {
+			count++
+		}
+	}
+	if count != 1 {
+		t.Fatalf("expected a logger with its own output to be registered once, got %d", count)
+	}
+	owner.Close()
+}
+
+func TestChildCloseLeavesTheParentOutputOpen(t *testing.T) {
+	f := tempLogFile(t)
+	log := CreateLogger("close-parent").SetOutputFile(f).SetToJSONOutput()
+	defer log.Close()
+
+	child := log.TagPair("request_id", "abc")
+	if err := child.Close(); err != nil {
+		t.Fatal(err)
+	}
+	locked, unlock := log.NewLoggerWithLock()
+	if err := locked.Close(); err != nil {
+		t.Fatal(err)
+	}
+	unlock()
+	log.Info("still open")
+
+	if records := decodeJSONLines(t, readLogFile(t, f)); len(records) != 1 {
+		t.Fatalf("expected the parent to keep writing, got %d records", len(records))
+	}
+
+	// a child given its own output closes that one
+	own := tempLogFile(t)
+	if err := log.Named("own").SetOutputFile(own).Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := own.Write([]byte("x")); err == nil {
+		t.Fatal("expected the child's own output to be closed")
+	}
+}
@@ -0,0 +1,68 @@
+package lib
+
+import (
+	"context"
+	"time"
+
+	ll "github.com/oresoftware/json-logging/jlog/level"
+	"github.com/oresoftware/json-logging/jlog/shared"
+	"github.com/oresoftware/json-logging/jlog/writer"
+)
+
+// how long Critical waits for the output to flush, when FlushOnCritical is set
+// (and how long a record logged with Opts{IsMustFlush: true} waits)
+var CriticalFlushTimeout = 5 * time.Second
+
+// Flush waits for queued (async) records to be written, then syncs the output if it supports it.
+func (l *Logger) Flush(ctx context.Context) error {
+	l.flushSamples()
+	l.flushRepeats()
+	return writer.Flush(ctx, l.outputFile())
+}
+
+// Close flushes and closes the output (stdout/stderr are never closed) and unregisters the logger.
+// Child loggers (and locked ones) share the output of the logger they came from, so for them Close only flushes,
+// unless SetOutput or SetOutputFile gave them their own.
+func (l *Logger) Close() error {
+	shared.UnregisterLogger(l)
+	l.flushSamples()
+	l.flushRepeats()
+	l.Mtx.RLock()
+	isShared := l.sharesOutput
+	l.Mtx.RUnlock()
+	out := l.outputFile()
+	if err := writer.Flush(context.Background(), out); err != nil || isShared {
+		return err
+	}
+	return writer.Close(out)
+}
+
+func (l *Logger) SetFlushOnCritical(b bool) *Logger {
+	l.Mtx.Lock()
+	defer l.Mtx.Unlock()
+	l.FlushOnCritical = b
+	return l
+}
+
+// flushAfter flushes after a Critical record when FlushOnCritical is set,
+// and after any record logged with Opts{IsMustFlush: true}
+func (l *Logger) flushAfter(level ll.LogLevel, opts *Opts) {
+	l.Mtx.RLock()
+	b := level.AtLeast(ll.CRITICAL) && l.FlushOnCritical
+	l.Mtx.RUnlock()
+
+	if opts != nil && opts.IsMustFlush {
+		b = true
+	}
+
+	if !b {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), CriticalFlushTimeout)
+	defer cancel()
+
+	if err := l.Flush(ctx); err != nil {
+		writeToStderr("json-logging: could not flush after log:", err)
+	}
+}
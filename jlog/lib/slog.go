@@ -0,0 +1,162 @@
+package lib
+
+import (
+	"context"
+	"log/slog"
+
+	jctx "github.com/oresoftware/json-logging/jlog/ctx"
+	hlpr "github.com/oresoftware/json-logging/jlog/helper"
+	ll "github.com/oresoftware/json-logging/jlog/level"
+)
+
+// SlogHandler is a slog.Handler that writes the same @bunion:v1 / pretty output as Logger.
+// Attrs (including WithAttrs/WithGroup) end up in the meta map, the message in the args array.
+// There is no MultiLogger equivalent, only a Logger can back a slog.Logger.
+// Records with a zero time get an empty date, as the slog.Handler contract asks.
+// Otherwise records go through the same steps as Log: sampling, stack traces, dedup and FlushOnCritical,
+// with the caller taken from the record's PC.
+type SlogHandler struct {
+	l      *Logger
+	meta   MF
+	prefix string
+}
+
+var _ slog.Handler = (*SlogHandler)(nil)
+
+// the key slog uses for args that are not key/value pairs
+const slogBadKey = "!BADKEY"
+
+func NewSlogHandler(l *Logger) *SlogHandler {
+	if l == nil {
+		l = DefaultLogger
+	}
+	return &SlogHandler{l: l, meta: MF{}}
+}
+
+// Slog returns a *slog.Logger which writes through this logger.
+func (l *Logger) Slog() *slog.Logger {
+	return slog.New(NewSlogHandler(l))
+}
+
+// SlogToLogLevel maps slog levels onto ll.LogLevel,
+// anything below slog.LevelDebug is TRACE and anything above slog.LevelError is CRITICAL.
+func SlogToLogLevel(level slog.Level) ll.LogLevel {
+	switch {
+	case level < slog.LevelDebug:
+		return ll.TRACE
+	case level < slog.LevelInfo:
+		return ll.DEBUG
+	case level < slog.LevelWarn:
+		return ll.INFO
+	case level < slog.LevelError:
+		return ll.WARN
+	case level == slog.LevelError:
+		return ll.ERROR
+	}
+	return ll.CRITICAL
+}
+
+func (h *SlogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.l.IsLevelEnabled(SlogToLogLevel(level))
+}
+
+func (h *SlogHandler) Handle(ctx context.Context, r slog.Record) error {
+	level := SlogToLogLevel(r.Level)
+	if !h.l.IsLevelEnabled(level) {
+		return nil
+	}
+
+	var attrs = MF{}
+	var args = []interface{}{r.Message}
+
+	r.Attrs(func(a slog.Attr) bool {
+		if a.Key == slogBadKey {
+			// non key/value args passed to slog, keep them as plain args
+			args = append(args, a.Value.Resolve().Any())
+			return true
+		}
+		addSlogAttr(attrs, h.prefix, a)
+		return true
+	})
+
+	// the attrs go last, so they win over the logger's meta fields, like a *MetaFields arg does
+	var meta = MF{}
+	for k, v := range *jctx.MetaFields(ctx).Map() {
+		meta[k] = v
+	}
+	for k, v := range h.meta {
+		meta[k] = v
+	}
+	for k, v := range attrs {
+		meta[k] = v
+	}
+	args = append(args, NewMetaFields(&meta))
+
+	// the same pipeline as Log, with the time and the call site of the slog record
+	h.l.logAt(r.Time, level, args, r.PC)
+	return nil
+}
+
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) < 1 {
+		return h
+	}
+	var z = h.clone()
+	for _, a := range attrs {
+		addSlogAttr(z.meta, z.prefix, a)
+	}
+	return z
+}
+
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	var z = h.clone()
+	z.prefix = h.prefix + name + "."
+	return z
+}
+
+func (h *SlogHandler) clone() *SlogHandler {
+	var m = make(MF, len(h.meta))
+	for k, v := range h.meta {
+		m[k] = v
+	}
+	return &SlogHandler{l: h.l, meta: m, prefix: h.prefix}
+}
+
+// groups are flattened into dotted keys, eg: "http.status"
+func addSlogAttr(m MF, prefix string, a slog.Attr) {
+	v := a.Value.Resolve()
+
+	if v.Kind() == slog.KindGroup {
+		var p = prefix
+		if a.Key != "" {
+			p = prefix + a.Key + "."
+		}
+		for _, ga := range v.Group() {
+			addSlogAttr(m, p, ga)
+		}
+		return
+	}
+
+	if a.Key == "" {
+		return
+	}
+
+	switch v.Kind() {
+	case slog.KindDuration:
+		m[prefix+a.Key] = v.Duration().String()
+	default:
+		if err, ok := v.Any().(error); ok {
+			// the cause chain, like error args, instead of {}
+			if e := hlpr.NewErrorInfo(err); e != nil {
+				m[prefix+a.Key] = e
+			} else {
+				m[prefix+a.Key] = nil
+			}
+			return
+		}
+		m[prefix+a.Key] = v.Any()
+	}
+}
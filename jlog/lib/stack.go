@@ -0,0 +1,47 @@
+package lib
+
+import (
+	hlpr "github.com/oresoftware/json-logging/jlog/helper"
+	ll "github.com/oresoftware/json-logging/jlog/level"
+)
+
+// SetStackTraceLevel attaches a stack trace to records at this level and above,
+// use a level above CRITICAL to turn stack traces off.
+func (l *Logger) SetStackTraceLevel(level ll.LogLevel) *Logger {
+	l.Mtx.Lock()
+	defer l.Mtx.Unlock()
+	l.StackTraceLevel = level
+	l.stackLevelSet = true
+	return l
+}
+
+// SetStackParams sets the max depth and the include/exclude package patterns of stack traces.
+func (l *Logger) SetStackParams(p StackParams) *Logger {
+	l.Mtx.Lock()
+	defer l.Mtx.Unlock()
+	l.StackParams = p
+	return l
+}
+
+// withStackTrace appends a StackTrace to args if the level (or the per call Opts) calls for one
+func (l *Logger) withStackTrace(level ll.LogLevel, args []interface{}, opts *Opts) []interface{} {
+	l.Mtx.RLock()
+	minLevel := l.StackTraceLevel
+	if minLevel == ll.TRACE && !l.stackLevelSet {
+		// unset, whichever way the logger was built
+		minLevel = ll.ERROR
+	}
+	p := l.StackParams
+	l.Mtx.RUnlock()
+
+	var isPrint = level.AtLeast(minLevel)
+	if opts != nil {
+		isPrint = (isPrint || opts.IsPrintStackTrace) && !opts.IsSkipStackTrace
+		p.Skip += opts.SkipFrames
+	}
+
+	if !isPrint {
+		return args
+	}
+	return append(args, StackTrace{Frames: hlpr.GetStackFrames(0, p)})
+}
@@ -0,0 +1,68 @@
+package mult
+
+import (
+	"context"
+
+	jctx "github.com/oresoftware/json-logging/jlog/ctx"
+	ll "github.com/oresoftware/json-logging/jlog/level"
+)
+
+// WithContext stashes this logger in the context, retrieve it with FromContext.
+func (l *MultiLogger) WithContext(ctx context.Context) context.Context {
+	return jctx.WithLogger(ctx, l)
+}
+
+// FromContext returns the logger stashed via WithContext, or DefaultLogger.
+func FromContext(ctx context.Context) *MultiLogger {
+	if z, ok := jctx.LoggerFrom(ctx).(*MultiLogger); ok && z != nil {
+		return z
+	}
+	return DefaultLogger
+}
+
+// the context fields go first, so that meta fields passed explicitly take precedence
+func withCtxFields(ctx context.Context, args []interface{}) []interface{} {
+	return append([]interface{}{jctx.MetaFields(ctx)}, args...)
+}
+
+func (l *MultiLogger) TraceCtx(ctx context.Context, args ...interface{}) {
+	if !l.IsLevelEnabled(ll.TRACE) {
+		return
+	}
+	l.Trace(withCtxFields(ctx, args)...)
+}
+
+func (l *MultiLogger) DebugCtx(ctx context.Context, args ...interface{}) {
+	if !l.IsLevelEnabled(ll.DEBUG) {
+		return
+	}
+	l.Debug(withCtxFields(ctx, args)...)
+}
+
+func (l *MultiLogger) InfoCtx(ctx context.Context, args ...interface{}) {
+	if !l.IsLevelEnabled(ll.INFO) {
+		return
+	}
+	l.Info(withCtxFields(ctx, args)...)
+}
+
+func (l *MultiLogger) WarnCtx(ctx context.Context, args ...interface{}) {
+	if !l.IsLevelEnabled(ll.WARN) {
+		return
+	}
+	l.Warn(withCtxFields(ctx, args)...)
+}
+
+func (l *MultiLogger) ErrorCtx(ctx context.Context, args ...interface{}) {
+	if !l.IsLevelEnabled(ll.ERROR) {
+		return
+	}
+	l.Error(withCtxFields(ctx, args)...)
+}
+
+func (l *MultiLogger) CriticalCtx(ctx context.Context, args ...interface{}) {
+	if !l.IsLevelEnabled(ll.CRITICAL) {
+		return
+	}
+	l.Critical(withCtxFields(ctx, args)...)
+}
@@ -0,0 +1,125 @@
+package mult
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"time"
+
+	ll "github.com/oresoftware/json-logging/jlog/level"
+	"github.com/oresoftware/json-logging/jlog/shared"
+	"github.com/oresoftware/json-logging/jlog/writer"
+)
+
+// how long Critical waits for the outputs to flush, when FlushOnCritical is set
+// (and how long a record logged with Opts{IsMustFlush: true} waits)
+var CriticalFlushTimeout = 5 * time.Second
+
+// the same writer may be used by several FileLevels
+func (l *MultiLogger) outputs() []io.Writer {
+	return outputsOf(l.allFiles())
+}
+
+// ownOutputs leaves out the outputs shared with the logger this one came from (see Child), Close does not close them
+func (l *MultiLogger) ownOutputs() []io.Writer {
+	l.Mtx.RLock()
+	var borrowed = map[*FileLevel]bool{}
+	for _, f := range l.parentFiles {
+		borrowed[f] = true
+	}
+	l.Mtx.RUnlock()
+
+	var files []*FileLevel
+	for _, f := range l.allFiles() {
+		if !borrowed[f] {
+			files = append(files, f)
+		}
+	}
+	return outputsOf(files)
+}
+
+func outputsOf(files []*FileLevel) []io.Writer {
+	var seen = map[io.Writer]bool{}
+	var results []io.Writer
+	for _, f := range files {
+		if f == nil {
+			continue
+		}
+		out := f.output()
+		if reflect.TypeOf(out).Comparable() {
+			if seen[out] {
+				continue
+			}
+			seen[out] = true
+		}
+		results = append(results, out)
+	}
+	return results
+}
+
+// Flush waits for queued (async) records to be written, then syncs every output that supports it.
+func (l *MultiLogger) Flush(ctx context.Context) error {
+	l.flushSamples()
+	l.flushRepeats()
+	var errs []error
+	for _, out := range l.outputs() {
+		if err := writer.Flush(ctx, out); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close flushes every output, closes the ones the logger owns (stdout/stderr are never closed) and unregisters the logger.
+// Child loggers (and locked ones) share the outputs of the logger they came from, so they only close the outputs added to them.
+func (l *MultiLogger) Close() error {
+	shared.UnregisterLogger(l)
+	l.flushSamples()
+	l.flushRepeats()
+	var errs []error
+	for _, out := range l.outputs() {
+		if err := writer.Flush(context.Background(), out); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	for _, out := range l.ownOutputs() {
+		if err := writer.Close(out); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (l *MultiLogger) SetFlushOnCritical(b bool) *MultiLogger {
+	l.Mtx.Lock()
+	defer l.Mtx.Unlock()
+	l.FlushOnCritical = b
+	return l
+}
+
+// flushAfter flushes after a Critical record when FlushOnCritical is set,
+// and after any record logged with Opts{IsMustFlush: true}
+func (l *MultiLogger) flushAfter(level ll.LogLevel, opts *Opts) {
+	l.Mtx.RLock()
+	b := level.AtLeast(ll.CRITICAL) && l.FlushOnCritical
+	l.Mtx.RUnlock()
+
+	if opts != nil && opts.IsMustFlush {
+		b = true
+	}
+
+	if !b {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), CriticalFlushTimeout)
+	defer cancel()
+
+	if err := l.Flush(ctx); err != nil {
+		l.writeToStderr("json-logging: could not flush after log:", err)
+	}
+}
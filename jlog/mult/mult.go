@@ -2,26 +2,28 @@ package mult
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	uuid "github.com/google/uuid"
 	au "github.com/oresoftware/json-logging/jlog/au"
+	"github.com/oresoftware/json-logging/jlog/dedup"
 	hlpr "github.com/oresoftware/json-logging/jlog/helper"
 	ll "github.com/oresoftware/json-logging/jlog/level"
+	"github.com/oresoftware/json-logging/jlog/sample"
 	"github.com/oresoftware/json-logging/jlog/shared"
 	"github.com/oresoftware/json-logging/jlog/stack"
 	"github.com/oresoftware/json-logging/jlog/writer"
+	"io"
 	"log"
 	"os"
 	"reflect"
-	"runtime"
 	"runtime/debug"
 	"sort"
 	"strconv"
 	"strings"
 	"sync"
-	"syscall"
 	"time"
 )
 
@@ -30,17 +32,14 @@ var safeStderr = writer.NewSafeWriter(os.Stderr)
 var lockStack = stack.NewStack()
 
 type FileLevel struct {
-	Level      ll.LogLevel
-	File       *os.File
-	Tags       *map[string]interface{}
-	lock       *sync.RWMutex
-	IsJSON     bool
-	isTrace    bool
-	isDebug    bool
-	isInfo     bool
-	isWarn     bool
-	isError    bool
-	isCritical bool
+	Level  ll.LogLevel
+	File   *os.File
+	Writer io.Writer // takes precedence over File, can be any io.Writer
+	Tags   *map[string]interface{}
+	lock   *sync.RWMutex
+	IsJSON bool
+	// guards Writer and Level once the FileLevel is in use, child loggers share it (see SetAsync, SetFileLevel)
+	mtx sync.RWMutex
 }
 
 type MultiLogger struct {
@@ -52,77 +51,97 @@ type MultiLogger struct {
 	LockUuid   string
 	EnvPrefix  string
 	Files      []*FileLevel
-	isTrace    bool
-	isDebug    bool
-	isInfo     bool
-	isWarn     bool
-	isError    bool
-	isCritical bool
+	// Name is the dotted name of the logger, eg db.pool, a level set for it or a prefix takes the place
+	// of the FileLevel thresholds (see Named)
+	Name string
+	// FlushOnCritical flushes/syncs the outputs before Critical returns, so crash logs are not lost
+	FlushOnCritical bool
+	// IsShowCaller adds the file:line:function of the logging call to the meta fields, under "caller"
+	IsShowCaller bool
+	// StackParams sets the depth and the package filters of stack traces
+	StackParams StackParams
+	// PanicParams says what Recover/Go do after logging a panic
+	PanicParams PanicParams
+	// Sampler drops records when sampling is on (see SetSampling), it is shared with child loggers
+	Sampler *sample.Sampler
+	// Deduper swallows repeated records when dedup is on (see SetDedupWindow), it is shared with child loggers
+	Deduper *dedup.Deduper
+	// records at this level and above get a StackTrace arg, the zero value means ERROR unless set with SetStackTraceLevel
+	StackTraceLevel ll.LogLevel
+	// stackLevelSet is true once SetStackTraceLevel is called, so TRACE can be told apart from unset
+	stackLevelSet bool
+	// the outputs of the logger a child (or locked) logger came from, Close flushes them but leaves them open
+	parentFiles []*FileLevel
 }
 
 type MultLoggerParams struct {
-	AppName    string
-	HostName   string
-	MetaFields *MetaFields
-	TimeZone   time.Location
-	LockUuid   string
-	EnvPrefix  string
-	Files      []*FileLevel
+	AppName         string
+	HostName        string
+	MetaFields      *MetaFields
+	TimeZone        time.Location
+	LockUuid        string
+	EnvPrefix       string
+	Files           []*FileLevel
+	Name            string
+	FlushOnCritical bool
+	IsShowCaller    bool
+	// StackParams sets the depth and the package filters of stack traces
+	StackParams StackParams
+	// PanicParams says what Recover/Go do after logging a panic
+	PanicParams PanicParams
+	// StackTraceLevel attaches a stack trace to records at this level and above, unset (TRACE) means ERROR,
+	// call SetStackTraceLevel(ll.TRACE) to get one on every record
+	StackTraceLevel ll.LogLevel
 }
 
 // TODO: create a goroutine for each Output path
 // write to that existing goroutine
 
-func getFileInfo(f *os.File) (string, error) {
+// output returns the sink for this FileLevel, Writer first, then File, then stdout
+func (f *FileLevel) output() io.Writer {
+	f.mtx.RLock()
+	defer f.mtx.RUnlock()
+	return f.sink()
+}
 
-	var fd1 = f.Fd()
-	var stat1 syscall.Stat_t
+// level returns the threshold of this FileLevel, see SetFileLevel
+func (f *FileLevel) level() ll.LogLevel {
+	f.mtx.RLock()
+	defer f.mtx.RUnlock()
+	return f.Level
+}
 
-	if err := syscall.Fstat(int(fd1), &stat1); err != nil {
-		return "", err
+// must hold f.mtx
+func (f *FileLevel) sink() io.Writer {
+	if f.Writer != nil {
+		return f.Writer
 	}
-
-	return strconv.Itoa(int(stat1.Dev)) + ":" + strconv.Itoa(int(stat1.Ino)), nil
+	if f.File == nil {
+		return os.Stdout
+	}
+	return f.File
 }
 
 func mapFileLevels(x []*FileLevel) []*FileLevel {
 
 	var results = []*FileLevel{}
-	var m1 = map[uintptr]*sync.RWMutex{}
-	var m2 = map[string]*sync.RWMutex{}
 
 	for _, z := range x {
 		if z == nil {
 			continue
 		}
 
-		if z.File == nil {
+		if z.File == nil && z.Writer == nil {
 			z.File = os.Stdout
 		}
 
-		if z.File.Fd() == os.Stdout.Fd() && !shared.IsTerminal {
+		if writer.IsStdout(z.output()) && !shared.IsTerminal {
 			z.IsJSON = true
 		}
 
-		var fd = z.File.Fd()
-		var fileInfo, err = getFileInfo(z.File)
-
-		if err == nil {
-			if _, ok := m2[fileInfo]; !ok {
-				m2[fileInfo] = &sync.RWMutex{}
-			}
-			z.lock = m2[fileInfo]
-			results = append(results, z)
-			continue
-		}
-
-		if _, ok := m1[fd]; !ok {
-			m1[fd] = &sync.RWMutex{}
-		}
-
-		z.lock = m1[fd]
+		// files share a lock per inode, other writers per pointer
+		z.lock = writer.LockFor(z.output())
 		results = append(results, z)
-
 	}
 
 	return results
@@ -186,14 +205,14 @@ func NewMultiLogger(p MultLoggerParams) *MultiLogger {
 			value := parts[1]
 			if strings.HasPrefix(key, p.EnvPrefix) {
 				result := strings.TrimPrefix(key, p.EnvPrefix)
-				(*metaFields.m)[result] = value
+				(*metaFields.Map())[result] = value
 			}
 		}
 	}
 
-	if p.MetaFields != nil && p.MetaFields.m != nil {
-		for k, v := range *p.MetaFields.m {
-			(*metaFields.m)[k] = v
+	if p.MetaFields != nil && p.MetaFields.Map() != nil {
+		for k, v := range *p.MetaFields.Map() {
+			(*metaFields.Map())[k] = v
 		}
 	}
 
@@ -203,26 +222,36 @@ func NewMultiLogger(p MultLoggerParams) *MultiLogger {
 	}
 
 	var l = &MultiLogger{
-		Mtx:        sync.RWMutex{},
-		AppName:    appName,
-		HostName:   hostName,
-		TimeZone:   p.TimeZone,
-		MetaFields: metaFields,
-		LockUuid:   p.LockUuid,
-		EnvPrefix:  p.EnvPrefix,
-		Files:      files,
-		isError:    true,
-		isWarn:     false,
-		isInfo:     false,
-		isDebug:    false,
-		isTrace:    false,
-		isCritical: true,
+		Mtx:             sync.RWMutex{},
+		AppName:         appName,
+		HostName:        hostName,
+		TimeZone:        p.TimeZone,
+		MetaFields:      metaFields,
+		LockUuid:        p.LockUuid,
+		EnvPrefix:       p.EnvPrefix,
+		Files:           files,
+		Name:            p.Name,
+		FlushOnCritical: p.FlushOnCritical,
+		IsShowCaller:    p.IsShowCaller,
+		StackParams:     p.StackParams,
+		StackTraceLevel: p.StackTraceLevel,
+		PanicParams:     p.PanicParams,
 	}
 
 	l.determineInitialLogLevels()
+	l.registerOwner()
 	return l
 }
 
+// registerOwner registers the logger if it owns an output, must hold l.Mtx
+func (l *MultiLogger) registerOwner() {
+	for _, f := range l.Files {
+		if f != nil {
+			shared.RegisterOwner(l, f.output())
+		}
+	}
+}
+
 func (l *MultiLogger) SetEnvPrefix(s string) *MultiLogger {
 	l.Mtx.Lock()
 	defer l.Mtx.Unlock()
@@ -239,30 +268,62 @@ func (l *MultiLogger) AddOutputFile(level ll.LogLevel, f *os.File) *MultiLogger
 	if f == nil {
 		f = os.Stdout
 	}
+	return l.addOutput(level, f, nil)
+}
 
+// AddOutput adds any io.Writer as an output, eg a bytes.Buffer, a net.Conn, a gzip.Writer.
+// JSON is used for non-file writers, since they are not terminals.
+func (l *MultiLogger) AddOutput(level ll.LogLevel, w io.Writer) *MultiLogger {
+	if f, ok := w.(*os.File); ok || w == nil {
+		return l.AddOutputFile(level, f)
+	}
+	return l.addOutput(level, nil, w)
+}
+
+func (l *MultiLogger) addOutput(level ll.LogLevel, f *os.File, w io.Writer) *MultiLogger {
 	l.Mtx.Lock()
 	defer l.Mtx.Unlock()
 
-	isJSON := f.Fd() == os.Stdout.Fd() && !shared.IsTerminal
+	isJSON := w != nil || (writer.IsStdout(f) && !shared.IsTerminal)
 
 	files := mapFileLevels(append(l.Files, &FileLevel{
-		Level:      level,
-		File:       f,
-		Tags:       nil,
-		lock:       nil,
-		IsJSON:     isJSON,
-		isTrace:    false,
-		isDebug:    false,
-		isInfo:     false,
-		isWarn:     false,
-		isError:    false,
-		isCritical: false,
+		Level:  level,
+		File:   f,
+		Writer: w,
+		Tags:   nil,
+		lock:   nil,
+		IsJSON: isJSON,
 	}))
 	l.Files = files
 	l.determineInitialLogLevels()
+	l.registerOwner()
 	return l
 }
 
+// SetFileLevel changes the threshold of the output at index i of Files, child loggers share
+// the outputs, so they get the new threshold too.
+func (l *MultiLogger) SetFileLevel(i int, level ll.LogLevel) *MultiLogger {
+	l.Mtx.RLock()
+	defer l.Mtx.RUnlock()
+	if i >= 0 && i < len(l.Files) && l.Files[i] != nil {
+		var f = l.Files[i]
+		f.mtx.Lock()
+		f.Level = level
+		f.mtx.Unlock()
+	}
+	return l
+}
+
+// FileLevelAt returns the threshold of the output at index i of Files, ok is false if there is none.
+func (l *MultiLogger) FileLevelAt(i int) (level ll.LogLevel, ok bool) {
+	l.Mtx.RLock()
+	defer l.Mtx.RUnlock()
+	if i >= 0 && i < len(l.Files) && l.Files[i] != nil {
+		return l.Files[i].level(), true
+	}
+	return 0, false
+}
+
 func (l *MultiLogger) SetMinLogLevel(f ll.LogLevel) *MultiLogger {
 	return l
 }
@@ -274,21 +335,21 @@ func (l *MultiLogger) SetMaxLogLevel(f ll.LogLevel) *MultiLogger {
 func (l *MultiLogger) RemoveTag(s string) *MultiLogger {
 	l.Mtx.Lock()
 	defer l.Mtx.Unlock()
-	delete(*l.MetaFields.m, s)
+	delete(*l.MetaFields.Map(), s)
 	return l
 }
 
 func (l *MultiLogger) AddTag(s string, v interface{}) *MultiLogger {
 	l.Mtx.Lock()
 	defer l.Mtx.Unlock()
-	(*l.MetaFields.m)[s] = v
+	(*l.MetaFields.Map())[s] = v
 	return l
 }
 
 func (l *MultiLogger) AddMetaField(s string, v interface{}) *MultiLogger {
 	l.Mtx.Lock()
 	defer l.Mtx.Unlock()
-	(*l.MetaFields.m)[s] = v
+	(*l.MetaFields.Map())[s] = v
 	return l
 }
 
@@ -324,37 +385,24 @@ func (l *MultiLogger) SetHostName(h string) *MultiLogger {
 	return l
 }
 
-func (l *MultiLogger) determineInitialLogLevels() {
-	///
-	l.isTrace = false
-	l.isDebug = false
-	l.isInfo = false
-	l.isWarn = false
-	l.isError = false
-	l.isCritical = false
+// SetShowCaller turns on/off recording the file, line and function of each logging call.
+func (l *MultiLogger) SetShowCaller(b bool) *MultiLogger {
+	l.Mtx.Lock()
+	defer l.Mtx.Unlock()
+	l.IsShowCaller = b
+	return l
+}
 
+// determineInitialLogLevels adds a stdout output if there are none, and sets up the outputs' locks,
+// a FileLevel gets the records at or above its Level, see fileAllowsLevel
+func (l *MultiLogger) determineInitialLogLevels() {
 	if len(l.Files) < 1 {
-
-		l.isTrace = true
-		l.isDebug = true
-		l.isInfo = true
-		l.isWarn = true
-		l.isError = true
-		l.isCritical = true
-
 		l.Files = append(l.Files, &FileLevel{
-			Level:      ll.TRACE,
-			File:       os.Stdout,
-			lock:       &sync.RWMutex{},
-			IsJSON:     !shared.IsTerminal,
-			isTrace:    true,
-			isDebug:    true,
-			isInfo:     true,
-			isWarn:     true,
-			isError:    true,
-			isCritical: true,
+			Level:  ll.TRACE,
+			File:   os.Stdout,
+			lock:   &sync.RWMutex{},
+			IsJSON: !shared.IsTerminal,
 		})
-
 		return
 	}
 
@@ -363,71 +411,13 @@ func (l *MultiLogger) determineInitialLogLevels() {
 			continue
 		}
 
-		if v.File == nil {
+		if v.File == nil && v.Writer == nil {
 			v.File = os.Stdout
 		}
 
 		if v.lock == nil {
-			v.lock = &sync.RWMutex{}
-		}
-
-		v.isTrace = false
-		v.isDebug = false
-		v.isInfo = false
-		v.isWarn = false
-		v.isError = false
-		v.isCritical = false
-
-		v.isCritical = true
-
-		switch v.Level {
-		case ll.CRITICAL:
-			l.isCritical = true
-		case ll.ERROR:
-			v.isError = true
-			l.isError = true
-			l.isCritical = true
-		case ll.WARN:
-			v.isWarn = true
-			l.isWarn = true
-			v.isError = true
-			l.isError = true
-			l.isCritical = true
-		case ll.INFO:
-			v.isWarn = true
-			l.isWarn = true
-			v.isInfo = true
-			l.isInfo = true
-			v.isError = true
-			l.isError = true
-			l.isCritical = true
-		case ll.DEBUG:
-			v.isWarn = true
-			l.isWarn = true
-			v.isInfo = true
-			l.isInfo = true
-			v.isDebug = true
-			l.isDebug = true
-			v.isError = true
-			l.isError = true
-			l.isCritical = true
-		case ll.TRACE:
-			v.isWarn = true
-			l.isWarn = true
-			v.isInfo = true
-			l.isInfo = true
-			v.isDebug = true
-			l.isDebug = true
-			v.isTrace = true
-			l.isTrace = true
-			v.isError = true
-			l.isError = true
-			l.isCritical = true
-		default:
-			panic("should have a log-level chosen")
+			v.lock = writer.LockFor(v.output())
 		}
-
-		v.isCritical = true
 	}
 }
 
@@ -435,38 +425,27 @@ func (l *MultiLogger) determineInitialLogLevels() {
 //	return New(AppName, forceJSON, hostName, envTokenPrefix)
 //}
 
-type KV struct {
-	Key   string
-	Value interface{}
-	*metaFieldsMarker
-}
-
-type M = map[string]interface{}
-type L = []KV
+type KV = shared.KV
+type M = shared.M
+type L = shared.L
+type MF = shared.MF
+type MetaFields = shared.MetaFields
+type LogId = shared.LogId
+type ErrorId = shared.ErrorId
+type Opts = shared.Opts
+type StackTrace = shared.StackTrace
+type Frame = hlpr.Frame
+type StackParams = hlpr.StackParams
+type PanicParams = shared.PanicParams
+type SampleParams = sample.Params
+type SampleRule = sample.Rule
 
 func NewMetaFields(m *MF) *MetaFields {
-	return &MetaFields{
-		metaFieldsMarker: mfMarker,
-		UniqueMarker:     "UniqueMarker(Brand)",
-		m:                m,
-	}
-}
-
-type logIdMarker struct{}
-
-var myLogIdMarker = &logIdMarker{}
-
-type LogId struct {
-	*logIdMarker
-	val string
-}
-
-func (x *LogId) IsLogId() bool {
-	return true
+	return shared.NewMetaFields(m)
 }
 
 func Id(v string) *LogId {
-	return &LogId{myLogIdMarker, v}
+	return shared.Id(v)
 }
 
 func (l *MultiLogger) Id(v string) *LogId {
@@ -474,30 +453,21 @@ func (l *MultiLogger) Id(v string) *LogId {
 }
 
 func levelEnabled(minLevel ll.LogLevel, level ll.LogLevel) bool {
-	return level >= minLevel
+	return level.AtLeast(minLevel)
 }
 
-func fileAllowsLevel(f *FileLevel, level ll.LogLevel) bool {
+// fileAllowsLevel uses the severity, so registered levels work like the builtin ones, a level set by name
+// (isNamed) takes the place of f.Level, so it can make a logger more verbose as well as less,
+// the threshold is moved by shared.ShiftLevels
+func fileAllowsLevel(f *FileLevel, level ll.LogLevel, named ll.LogLevel, isNamed bool) bool {
 	if f == nil {
 		return false
 	}
-
-	switch level {
-	case ll.TRACE:
-		return f.isTrace
-	case ll.DEBUG:
-		return f.isDebug
-	case ll.INFO:
-		return f.isInfo
-	case ll.WARN:
-		return f.isWarn
-	case ll.ERROR:
-		return f.isError
-	case ll.CRITICAL:
-		return f.isCritical
-	default:
-		return false
+	var threshold = f.level()
+	if isNamed {
+		threshold = named
 	}
+	return level.AtLeast(shared.Shifted(threshold))
 }
 
 func V(level ll.LogLevel) bool {
@@ -516,8 +486,9 @@ func (l *MultiLogger) IsLevelEnabled(level ll.LogLevel) bool {
 	l.Mtx.RLock()
 	defer l.Mtx.RUnlock()
 
+	named, isNamed := shared.NamedLevel(l.Name)
 	for _, f := range l.Files {
-		if fileAllowsLevel(f, level) {
+		if fileAllowsLevel(f, level, named, isNamed) {
 			return true
 		}
 	}
@@ -525,6 +496,56 @@ func (l *MultiLogger) IsLevelEnabled(level ll.LogLevel) bool {
 	return false
 }
 
+// EffectiveLevel is the level set for the logger's name (or the closest prefix), or else the lowest FileLevel threshold,
+// moved by shared.ShiftLevels.
+func (l *MultiLogger) EffectiveLevel() ll.LogLevel {
+	l.Mtx.RLock()
+	defer l.Mtx.RUnlock()
+
+	if v, ok := shared.NamedLevel(l.Name); ok {
+		return shared.Shifted(v)
+	}
+	var level = ll.CRITICAL
+	for i, f := range l.Files {
+		if v := f.level(); i == 0 || !v.AtLeast(level) {
+			level = v
+		}
+	}
+	return shared.Shifted(level)
+}
+
+// Describe is a snapshot of the logger's levels and outputs.
+func (l *MultiLogger) Describe() shared.LoggerInfo {
+	var info = shared.LoggerInfo{Level: l.EffectiveLevel().String(), Outputs: []shared.OutputInfo{}}
+
+	l.Mtx.RLock()
+	defer l.Mtx.RUnlock()
+	info.App = l.AppName
+	info.Name = l.Name
+	named, isNamed := shared.NamedLevel(l.Name)
+	if isNamed {
+		info.NamedLevel = named.String()
+	}
+	for _, f := range l.Files {
+		var threshold = f.level()
+		if isNamed {
+			threshold = named
+		}
+		info.Outputs = append(info.Outputs, shared.DescribeOutput(f.output(), shared.Shifted(threshold), f.IsJSON))
+	}
+	return info
+}
+
+// SetNamedLevel sets the level of the loggers with this name and the ones below it, eg db covers db.pool.
+func SetNamedLevel(name string, level ll.LogLevel) {
+	shared.SetNamedLevel(name, level)
+}
+
+// ClearNamedLevel removes a level set with SetNamedLevel or the jlog_levels env var.
+func ClearNamedLevel(name string) {
+	shared.ClearNamedLevel(name)
+}
+
 func (l *MultiLogger) IsTraceEnabled() bool {
 	return l.IsLevelEnabled(ll.TRACE)
 }
@@ -553,9 +574,10 @@ func (l *MultiLogger) filesForLevel(level ll.LogLevel) []*FileLevel {
 	l.Mtx.RLock()
 	defer l.Mtx.RUnlock()
 
+	named, isNamed := shared.NamedLevel(l.Name)
 	files := make([]*FileLevel, 0, len(l.Files))
 	for _, f := range l.Files {
-		if fileAllowsLevel(f, level) {
+		if fileAllowsLevel(f, level, named, isNamed) {
 			files = append(files, f)
 		}
 	}
@@ -569,7 +591,7 @@ func (l *MultiLogger) allFiles() []*FileLevel {
 	return append([]*FileLevel(nil), l.Files...)
 }
 
-func (l *MultiLogger) writeOutput(file *os.File, b []byte) {
+func (l *MultiLogger) writeOutput(file io.Writer, b []byte) {
 	if file == nil {
 		file = os.Stdout
 	}
@@ -588,29 +610,118 @@ func (l *MultiLogger) writeOutput(file *os.File, b []byte) {
 	}
 }
 
+// writeRecord is writeOutput for log records, the level is passed on to level-aware writers (async)
+func (l *MultiLogger) writeRecord(file io.Writer, level ll.LogLevel, b []byte) {
+	if file == nil {
+		file = os.Stdout
+	}
+
+	l.Mtx.RLock()
+	isLockedLogger := l.LockUuid != ""
+	l.Mtx.RUnlock()
+
+	if !isLockedLogger {
+		shared.M1.RLock()
+		defer shared.M1.RUnlock()
+	}
+
+	if _, err := writer.WriteLevel(file, level, b); err != nil {
+		l.writeToStderr("json-logging: could not write log output:", err)
+	}
+}
+
+// SetAsync turns on async mode for every output: records are serialized on the calling goroutine,
+// queued in a bounded ring buffer and written in batches by a background goroutine.
+func (l *MultiLogger) SetAsync(p writer.AsyncParams) *MultiLogger {
+	l.Mtx.Lock()
+	defer l.Mtx.Unlock()
+	for _, f := range l.Files {
+		if f == nil {
+			continue
+		}
+		f.mtx.Lock()
+		if _, ok := f.sink().(*writer.AsyncWriter); !ok {
+			var z = p
+			z.Writer = f.sink()
+			f.Writer = writer.NewAsyncWriter(z)
+		}
+		f.mtx.Unlock()
+	}
+	l.registerOwner()
+	return l
+}
+
+// synchronous returns f, or a copy of it which writes straight to the destination if f is async
+func (f *FileLevel) synchronous() *FileLevel {
+	aw, ok := f.output().(*writer.AsyncWriter)
+	if !ok {
+		return f
+	}
+	return &FileLevel{
+		Level:  f.level(),
+		File:   f.File,
+		Writer: aw.Destination(),
+		Tags:   f.Tags,
+		lock:   f.lock,
+		IsJSON: f.IsJSON,
+	}
+}
+
+// AsyncStats sums the queue counters (including dropped records) of the async outputs.
+func (l *MultiLogger) AsyncStats() writer.AsyncStats {
+	var z writer.AsyncStats
+	for _, f := range l.allFiles() {
+		if f == nil {
+			continue
+		}
+		if aw, ok := f.output().(*writer.AsyncWriter); ok {
+			s := aw.Stats()
+			z.Queued += s.Queued
+			z.Written += s.Written
+			z.Dropped += s.Dropped
+			z.Errors += s.Errors
+		}
+	}
+	return z
+}
+
 func (l *MultiLogger) NewLoggerWithLock() (*MultiLogger, func()) {
 	shared.M1.Lock()
 	var id = uuid.New().String()
 	lockStack.Push(&stack.StackItem{
 		Id: id,
 	})
+	// the queues are drained and the locked logger skips them, so that nothing else gets written
+	// until the lock is released, the other loggers are blocked before they can queue a record
+	if err := writer.FlushAll(context.Background()); err != nil {
+		l.writeToStderr("json-logging: could not flush async outputs:", err)
+	}
 	l.Mtx.RLock()
-	files := append([]*FileLevel(nil), l.Files...)
+	files := make([]*FileLevel, 0, len(l.Files))
+	for _, f := range l.Files {
+		if f != nil {
+			files = append(files, f.synchronous())
+		}
+	}
 	var z = MultiLogger{
-		Mtx:        sync.RWMutex{},
-		AppName:    l.AppName,
-		HostName:   l.HostName,
-		TimeZone:   l.TimeZone,
-		MetaFields: l.MetaFields,
-		LockUuid:   id,
-		EnvPrefix:  l.EnvPrefix,
-		Files:      files,
-		isTrace:    l.isTrace,
-		isDebug:    l.isDebug,
-		isInfo:     l.isInfo,
-		isWarn:     l.isWarn,
-		isError:    l.isError,
-		isCritical: l.isCritical,
+		Mtx:             sync.RWMutex{},
+		AppName:         l.AppName,
+		HostName:        l.HostName,
+		TimeZone:        l.TimeZone,
+		MetaFields:      l.MetaFields,
+		LockUuid:        id,
+		EnvPrefix:       l.EnvPrefix,
+		Files:           files,
+		Name:            l.Name,
+		FlushOnCritical: l.FlushOnCritical,
+		IsShowCaller:    l.IsShowCaller,
+		StackParams:     l.StackParams,
+		StackTraceLevel: l.StackTraceLevel,
+		stackLevelSet:   l.stackLevelSet,
+		PanicParams:     l.PanicParams,
+		Sampler:         l.Sampler,
+		Deduper:         l.Deduper,
+		parentFiles:     files,
 	}
 	l.Mtx.RUnlock()
 	return &z, z.unlock
@@ -647,7 +758,7 @@ func (l *MultiLogger) Child(m *map[string]interface{}) *MultiLogger {
 	defer l.Mtx.RUnlock()
 
 	var z = make(map[string]interface{})
-	for k, v := range *l.MetaFields.m {
+	for k, v := range *l.MetaFields.Map() {
 		z[k] = hlpr.CopyAndDereference(v)
 	}
 
@@ -656,20 +767,24 @@ func (l *MultiLogger) Child(m *map[string]interface{}) *MultiLogger {
 	}
 
 	return &MultiLogger{
-		Mtx:        sync.RWMutex{},
-		AppName:    l.AppName,
-		HostName:   l.HostName,
-		TimeZone:   l.TimeZone,
-		MetaFields: NewMetaFields(&z),
-		LockUuid:   l.LockUuid,
-		EnvPrefix:  l.EnvPrefix,
-		Files:      l.Files,
-		isTrace:    l.isTrace,
-		isDebug:    l.isDebug,
-		isInfo:     l.isInfo,
-		isWarn:     l.isWarn,
-		isError:    l.isError,
-		isCritical: l.isCritical,
+		Mtx:             sync.RWMutex{},
+		AppName:         l.AppName,
+		HostName:        l.HostName,
+		TimeZone:        l.TimeZone,
+		MetaFields:      NewMetaFields(&z),
+		LockUuid:        l.LockUuid,
+		EnvPrefix:       l.EnvPrefix,
+		Files:           l.Files,
+		Name:            l.Name,
+		FlushOnCritical: l.FlushOnCritical,
+		IsShowCaller:    l.IsShowCaller,
+		StackParams:     l.StackParams,
+		StackTraceLevel: l.StackTraceLevel,
+		stackLevelSet:   l.stackLevelSet,
+		PanicParams:     l.PanicParams,
+		Sampler:         l.Sampler,
+		Deduper:         l.Deduper,
+		parentFiles:     l.Files,
 	}
 }
 
@@ -681,37 +796,42 @@ func (l *MultiLogger) Create(m *map[string]interface{}) *MultiLogger {
 	return l.Child(m)
 }
 
-func (l *MultiLogger) getPrettyString(level ll.LogLevel, m *MetaFields, args *[]interface{}) string {
-
-	l.Mtx.RLock()
-	appName := l.AppName
-	l.Mtx.RUnlock()
+var (
+	_ shared.Logger     = (*MultiLogger)(nil)
+	_ shared.ChildMaker = (*MultiLogger)(nil)
+	_ shared.Namer      = (*MultiLogger)(nil)
+	_ shared.Locker     = (*MultiLogger)(nil)
+	_ shared.Describer  = (*MultiLogger)(nil)
+	_ shared.Flusher    = (*MultiLogger)(nil)
+	_ io.Closer         = (*MultiLogger)(nil)
+	_ shared.Recoverer  = (*MultiLogger)(nil)
+	_ shared.Printer    = (*MultiLogger)(nil)
+)
 
-	date := time.Now().UTC().Format("15:04:05.000000")
-	stylizedLevel := "<undefined>"
+func (l *MultiLogger) ChildLogger(m *map[string]interface{}) shared.Logger {
+	return l.Child(m)
+}
 
-	switch level {
+func (l *MultiLogger) TagPairLogger(k string, v interface{}) shared.Logger {
+	return l.TagPair(k, v)
+}
 
-	case ll.ERROR:
-		stylizedLevel = au.Col.Underline(au.Col.Bold(au.Col.Red("ERROR"))).String()
-		break
+func (l *MultiLogger) NamedLogger(name string) shared.Logger {
+	return l.Named(name)
+}
 
-	case ll.WARN:
-		stylizedLevel = au.Col.Magenta("WARN").String()
-		break
+func (l *MultiLogger) LockedLogger() (shared.Logger, func()) {
+	return l.NewLoggerWithLock()
+}
 
-	case ll.DEBUG:
-		stylizedLevel = au.Col.Bold("DEBUG").String()
-		break
+func (l *MultiLogger) getPrettyString(level ll.LogLevel, m *MetaFields, args *[]interface{}) string {
 
-	case ll.INFO:
-		stylizedLevel = au.Col.Gray(12, "INFO").String()
-		break
+	l.Mtx.RLock()
+	appName := l.AppName
+	l.Mtx.RUnlock()
 
-	case ll.TRACE:
-		stylizedLevel = au.Col.Gray(4, "TRACE").String()
-		break
-	}
+	date := time.Now().UTC().Format("15:04:05.000000")
+	stylizedLevel := level.Pretty()
 
 	var b strings.Builder
 
@@ -723,6 +843,11 @@ func (l *MultiLogger) getPrettyString(level ll.LogLevel, m *MetaFields, args *[]
 	b.WriteString(au.Col.Italic(appName).String())
 	b.WriteString(" ")
 
+	if c, ok := hlpr.CallerFromMeta((*m.Map())["caller"]); ok {
+		b.WriteString(callerLink(c))
+		b.WriteString(" ")
+	}
+
 	size := 0
 
 	for _, v := range *args {
@@ -734,6 +859,16 @@ func (l *MultiLogger) getPrettyString(level ll.LogLevel, m *MetaFields, args *[]
 			continue
 		}
 
+		if frames, ok := hlpr.FramesFromArg(v); ok {
+			b.WriteString(hlpr.PrettyFrames(frames))
+			continue
+		}
+
+		if e, ok := hlpr.ErrorFromArg(v); ok {
+			b.WriteString(hlpr.PrettyError(e) + " ")
+			continue
+		}
+
 		val := reflect.ValueOf(v)
 		var kind = reflect.TypeOf(v).Kind()
 
@@ -766,7 +901,7 @@ func (l *MultiLogger) getPrettyString(level ll.LogLevel, m *MetaFields, args *[]
 			l.writeToStderr("771c710b-aba2-46ef-9126-c26d3dfe7925", err)
 		}
 
-		if !primitive && (level == ll.TRACE || level == ll.DEBUG) {
+		if !primitive && !level.AtLeast(ll.INFO) {
 
 			if _, err := b.WriteString("\n"); err != nil {
 				l.writeToStderr("18614292-658f-42a5-81e7-593e941ea857", err)
@@ -813,7 +948,7 @@ func (l *MultiLogger) F(s string, args ...interface{}) string {
 func (l *MultiLogger) writeJSON(level ll.LogLevel, mf *MetaFields, args *[]interface{}) {
 
 	date := time.Now().UTC().Format("2006-01-02 15:04:05.000000")
-	var strLevel = shared.LevelToString[level]
+	var strLevel = level.JSONName()
 	var pid = shared.PID
 
 	if mf == nil {
@@ -839,18 +974,17 @@ func (l *MultiLogger) writeJSON(level ll.LogLevel, mf *MetaFields, args *[]inter
 			continue
 		}
 
-		if v.File == nil {
-			v.File = os.Stdout
-		}
-
 		if v.IsJSON {
 			if !jsonReady {
-				buf, err := json.Marshal([8]interface{}{"@bunion:v1", appName, strLevel, pid, hostName, date, mf.m, *args})
+				buf, err := json.Marshal([8]interface{}{"@bunion:v1", appName, strLevel, pid, hostName, date, mf.Map(), *args})
 
 				if err != nil {
-					_, file, line, _ := runtime.Caller(3)
+					var where = "<unknown caller>"
+					if c := hlpr.GetCaller(0); c != nil {
+						where = "file://" + c.String()
+					}
 
-					DefaultLogger.Warn("could not marshal the slice:", err.Error(), "file://"+file+":"+strconv.Itoa(line))
+					l.writeToStderr("json-logging: could not marshal the slice:", err.Error(), where)
 
 					var cache = map[uintptr]interface{}{}
 					var cleaned = make([]interface{}, 0, len(*args))
@@ -861,7 +995,7 @@ func (l *MultiLogger) writeJSON(level ll.LogLevel, mf *MetaFields, args *[]inter
 						cleaned = append(cleaned, c)
 					}
 
-					buf, err = json.Marshal([8]interface{}{"@bunion:v1", appName, strLevel, pid, hostName, date, mf.m, cleaned})
+					buf, err = json.Marshal([8]interface{}{"@bunion:v1", appName, strLevel, pid, hostName, date, mf.Map(), cleaned})
 
 					if err != nil {
 						l.writeToStderr(errors.New("Json-Logging: could not marshal the slice: " + err.Error()))
@@ -873,14 +1007,14 @@ func (l *MultiLogger) writeJSON(level ll.LogLevel, mf *MetaFields, args *[]inter
 				jsonReady = true
 			}
 
-			l.writeOutput(v.File, jsonBuf)
+			l.writeRecord(v.output(), level, jsonBuf)
 			continue
 		}
 
 		if prettyBuf == nil {
 			prettyBuf = []byte(l.getPrettyString(level, mf, args))
 		}
-		l.writeOutput(v.File, prettyBuf)
+		l.writeRecord(v.output(), level, prettyBuf)
 	}
 
 }
@@ -902,10 +1036,41 @@ func (l *MultiLogger) PrintEnv() {
 }
 
 func (l *MultiLogger) writeSwitchForFormattedString(level ll.LogLevel, m *MetaFields, s *[]interface{}) {
-	l.writeJSON(level, m, s)
+	if m == nil {
+		m = NewMetaFields(&MF{})
+	}
+	l.addCaller(m, nil)
+	if !l.isRepeat(level, m, s) {
+		l.writeJSON(level, m, s)
+	}
+}
+
+func (l *MultiLogger) addCaller(mf *MetaFields, opts *Opts) {
+	l.Mtx.RLock()
+	isShowCaller := l.IsShowCaller
+	l.Mtx.RUnlock()
+
+	if isShowCaller {
+		var skip = 0
+		if opts != nil {
+			skip = opts.SkipFrames
+		}
+		if c := hlpr.GetCaller(skip); c != nil {
+			(*mf.Map())["caller"] = c
+		}
+	}
+}
+
+// callerLink renders a caller as a dimmed file:line, which is a hyperlink to the file on terminals that support it
+func callerLink(c *hlpr.Caller) string {
+	var text = c.ShortFile() + ":" + strconv.Itoa(c.Line)
+	return au.Col.Faint(au.Col.Hyperlink(text, "file://"+c.File)).String()
 }
 
 func (l *MultiLogger) writeSwitch(level ll.LogLevel, m *MetaFields, args *[]interface{}) {
+	if l.isRepeat(level, m, args) {
+		return
+	}
 	l.writeJSON(level, m, args)
 }
 
@@ -945,34 +1110,54 @@ func (l *MultiLogger) RawJSON(args ...interface{}) {
 	l.writeOutput(os.Stdout, b.Bytes())
 }
 
-func (l *MultiLogger) getMetaFields(args *[]interface{}) (*MetaFields, []interface{}) {
+func (l *MultiLogger) getMetaFields(args *[]interface{}) (*MetaFields, []interface{}, *Opts) {
 	var newArgs = []interface{}{}
 	var m = MF{}
 	var mf = NewMetaFields(&m)
 
 	l.Mtx.RLock()
-	for k, v := range *l.MetaFields.m {
+	for k, v := range *l.MetaFields.Map() {
 		m[k] = v
 	}
 	l.Mtx.RUnlock()
 
 	hasLogId := false
+	var opts *Opts
 
 	for _, x := range *args {
 		if z, ok := x.(MetaFields); ok {
-			for k, v := range *z.m {
+			for k, v := range *z.Map() {
 				m[k] = v
 			}
 		} else if z, ok := x.(*MetaFields); ok {
-			for k, v := range *z.m {
+			for k, v := range *z.Map() {
 				m[k] = v
 			}
 		} else if z, ok := x.(*LogId); ok {
-			m["log_id"] = z.val
+			m["log_id"] = z.Val
 			hasLogId = true
 		} else if z, ok := x.(LogId); ok {
-			m["log_id"] = z.val
+			m["log_id"] = z.Val
 			hasLogId = true
+		} else if z, ok := x.(*ErrorId); ok {
+			if z != nil {
+				m["error_id"] = z.Id
+			}
+		} else if z, ok := x.(ErrorId); ok {
+			m["error_id"] = z.Id
+		} else if z, ok := x.(*Opts); ok {
+			if z != nil {
+				opts = shared.MergeOpts(opts, *z)
+			}
+		} else if z, ok := x.(Opts); ok {
+			opts = shared.MergeOpts(opts, z)
+		} else if err, ok := x.(error); ok {
+			// expanded into the cause chain, for both JSON and pretty output
+			if e := hlpr.NewErrorInfo(err); e != nil {
+				newArgs = append(newArgs, e)
+			} else {
+				newArgs = append(newArgs, nil)
+			}
 		} else {
 			newArgs = append(newArgs, x)
 		}
@@ -982,141 +1167,66 @@ func (l *MultiLogger) getMetaFields(args *[]interface{}) (*MetaFields, []interfa
 		fmt.Println("missing log id:", string(debug.Stack()))
 	}
 
-	return mf, newArgs
+	l.addCaller(mf, opts)
+	return mf, newArgs, opts
 }
 
 func (l *MultiLogger) Info(args ...interface{}) {
-	if !l.IsLevelEnabled(ll.INFO) {
-		return
-	}
-	var meta, newArgs = l.getMetaFields(&args)
-	l.writeSwitch(ll.INFO, meta, &newArgs)
+	l.Log(ll.INFO, args...)
 }
 
 func (l *MultiLogger) Warn(args ...interface{}) {
-	if !l.IsLevelEnabled(ll.WARN) {
-		return
-	}
-	var meta, newArgs = l.getMetaFields(&args)
-	l.writeSwitch(ll.WARN, meta, &newArgs)
+	l.Log(ll.WARN, args...)
 }
 
 func (l *MultiLogger) Error(args ...interface{}) {
-	if !l.IsLevelEnabled(ll.ERROR) {
-		return
-	}
-	var meta, newArgs = l.getMetaFields(&args)
-	filteredStackTrace := hlpr.GetFilteredStacktrace()
-	newArgs = append(newArgs, StackTrace{filteredStackTrace})
-	l.writeSwitch(ll.ERROR, meta, &newArgs)
+	l.Log(ll.ERROR, args...)
 }
 
 func (l *MultiLogger) Debug(args ...interface{}) {
-	if !l.IsLevelEnabled(ll.DEBUG) {
-		return
-	}
-	var meta, newArgs = l.getMetaFields(&args)
-	l.writeSwitch(ll.DEBUG, meta, &newArgs)
+	l.Log(ll.DEBUG, args...)
 }
 
-func (l *MultiLogger) Trace(args ...interface{}) {
-	if !l.IsLevelEnabled(ll.TRACE) {
+// Log writes a record at any level, including the ones added with ll.Register.
+func (l *MultiLogger) Log(level ll.LogLevel, args ...interface{}) {
+	if !l.IsLevelEnabled(level) {
 		return
 	}
-	var meta, newArgs = l.getMetaFields(&args)
-	l.writeSwitch(ll.TRACE, meta, &newArgs)
-}
-
-func (l *MultiLogger) Critical(args ...interface{}) {
-	if !l.IsLevelEnabled(ll.CRITICAL) {
+	if l.isSampledOut(level, sample.Template(args), shared.SkipFrames(args)) {
 		return
 	}
-	var meta, newArgs = l.getMetaFields(&args)
-	filteredStackTrace := hlpr.GetFilteredStacktrace()
-	newArgs = append(newArgs, StackTrace{filteredStackTrace})
-	l.writeSwitch(ll.CRITICAL, meta, &newArgs)
+	var meta, newArgs, opts = l.getMetaFields(&args)
+	newArgs = l.withStackTrace(level, newArgs, opts)
+	l.writeSwitch(level, meta, &newArgs)
+	l.flushAfter(level, opts)
 }
 
-type errorIdMarker struct{}
-
-var eidMarker = &errorIdMarker{}
-
-type ErrorId struct {
-	Id            string
-	errorIdMarker *errorIdMarker
+func (l *MultiLogger) Trace(args ...interface{}) {
+	l.Log(ll.TRACE, args...)
 }
 
-type Opts struct {
-	IsPrintStackTrace bool
-	errorIdMarker     *errorIdMarker
+func (l *MultiLogger) Critical(args ...interface{}) {
+	l.Log(ll.CRITICAL, args...)
 }
 
 func ErrId(id string) *ErrorId {
-	return &ErrorId{
-		id, eidMarker,
-	}
+	return shared.ErrId(id)
 }
 
 func ErrOpts(id string) *ErrorId {
-	return &ErrorId{
-		id, eidMarker,
-	}
-}
-
-type StackTrace struct {
-	ErrorTrace *[]string
-}
-
-type MF = map[string]interface{}
-
-// brand the below struct with unique ref
-type metaFieldsMarker struct{}
-
-var mfMarker = &metaFieldsMarker{}
-
-type MetaFields struct {
-	*metaFieldsMarker
-	UniqueMarker string
-	m            *MF
+	return shared.ErrOpts(id)
 }
 
 func MetaPairs(
 	k1 string, v1 interface{},
 	args ...interface{}) *MetaFields {
-	return MP(k1, v1, args...)
+	return shared.MP(k1, v1, args...)
 }
 
 func MP(
 	k1 string, v1 interface{},
 	args ...interface{}) *MetaFields {
-
-	m := make(map[string]interface{})
-	nargs := append([]interface{}{k1, v1}, args...) // prepend the first two arguments to new slice
-
-	currKey := ""
-
-	for i, a := range nargs {
-
-		if i%2 == 0 {
-			// operate on keys
-			v, ok := a.(string)
-			if ok {
-				currKey = v
-			} else {
-				panic("even arguments must be strings, odd arguments are interface{}")
-			}
-			if len(nargs) < i+2 {
-				panic("a key needs a respective value.")
-			}
-			continue
-		}
-
-		// operate on values
-		m[currKey] = a
-	}
-
-	return NewMetaFields(&m)
-
+	return shared.MP(k1, v1, args...)
 }
 
 func (l *MultiLogger) TagPair(k string, v interface{}) *MultiLogger {
@@ -1124,60 +1234,62 @@ func (l *MultiLogger) TagPair(k string, v interface{}) *MultiLogger {
 	return l.Child(&z)
 }
 
+// Named is a child logger with the name appended to this one's, eg db => db.pool,
+// the records get the full name under "logger".
+func (l *MultiLogger) Named(name string) *MultiLogger {
+	l.Mtx.RLock()
+	var fullName = shared.JoinName(l.Name, name)
+	l.Mtx.RUnlock()
+	var z = l.Child(&map[string]interface{}{"logger": fullName})
+	z.Name = fullName
+	return z
+}
+
 func (l *MultiLogger) Tags(z *map[string]interface{}) *MultiLogger {
 	return l.Create(z)
 }
 
 func (l *MultiLogger) ErrorF(s string, args ...interface{}) {
-	if !l.IsLevelEnabled(ll.ERROR) {
-		return
-	}
-	filteredStackTrace := hlpr.GetFilteredStacktrace()
-	formattedString := fmt.Sprintf(s, args...)
-	l.writeSwitchForFormattedString(ll.ERROR, nil, &[]interface{}{formattedString, StackTrace{filteredStackTrace}})
+	l.LogF(ll.ERROR, s, args...)
 }
 
 func (l *MultiLogger) WarnF(s string, args ...interface{}) {
-	if !l.IsLevelEnabled(ll.WARN) {
-		return
-	}
-	l.writeSwitchForFormattedString(ll.WARN, nil, &[]interface{}{fmt.Sprintf(s, args...)})
+	l.LogF(ll.WARN, s, args...)
 }
 
 func (l *MultiLogger) InfoF(s string, args ...interface{}) {
-	if !l.IsLevelEnabled(ll.INFO) {
-		return
-	}
-	l.writeSwitchForFormattedString(ll.INFO, nil, &[]interface{}{fmt.Sprintf(s, args...)})
+	l.LogF(ll.INFO, s, args...)
 }
 
 func (l *MultiLogger) DebugF(s string, args ...interface{}) {
-	if !l.IsLevelEnabled(ll.DEBUG) {
+	l.LogF(ll.DEBUG, s, args...)
+}
+
+// LogF is Log with a format string.
+func (l *MultiLogger) LogF(level ll.LogLevel, s string, args ...interface{}) {
+	if !l.IsLevelEnabled(level) {
 		return
 	}
-	l.writeSwitchForFormattedString(ll.DEBUG, nil, &[]interface{}{fmt.Sprintf(s, args...)})
+	if l.isSampledOut(level, s, 0) {
+		return
+	}
+	var newArgs = l.withStackTrace(level, []interface{}{fmt.Sprintf(s, args...)}, nil)
+	l.writeSwitchForFormattedString(level, nil, &newArgs)
+	l.flushAfter(level, nil)
 }
 
 func (l *MultiLogger) TraceF(s string, args ...interface{}) {
-	if !l.IsLevelEnabled(ll.TRACE) {
-		return
-	}
-	l.writeSwitchForFormattedString(ll.TRACE, nil, &[]interface{}{fmt.Sprintf(s, args...)})
+	l.LogF(ll.TRACE, s, args...)
 }
 
 func (l *MultiLogger) CriticalF(s string, args ...interface{}) {
-	if !l.IsLevelEnabled(ll.CRITICAL) {
-		return
-	}
-	filteredStackTrace := hlpr.GetFilteredStacktrace()
-	formattedString := fmt.Sprintf(s, args...)
-	l.writeSwitchForFormattedString(ll.CRITICAL, nil, &[]interface{}{formattedString, StackTrace{filteredStackTrace}})
+	l.LogF(ll.CRITICAL, s, args...)
 }
 
 func (l *MultiLogger) NewLine() {
 	for _, n := range l.allFiles() {
 		if n != nil {
-			l.writeOutput(n.File, []byte("\n"))
+			l.writeOutput(n.output(), []byte("\n"))
 		}
 	}
 }
@@ -1189,7 +1301,7 @@ func (l *MultiLogger) Spaces(num int32) {
 	buf := []byte(strings.Repeat(" ", int(num)))
 	for _, n := range l.allFiles() {
 		if n != nil {
-			l.writeOutput(n.File, buf)
+			l.writeOutput(n.output(), buf)
 		}
 	}
 }
@@ -1201,7 +1313,7 @@ func (l *MultiLogger) Tabs(num int32) {
 	buf := []byte(strings.Repeat("\t", int(num)))
 	for _, n := range l.allFiles() {
 		if n != nil {
-			l.writeOutput(n.File, buf)
+			l.writeOutput(n.output(), buf)
 		}
 	}
 }
@@ -1226,7 +1338,7 @@ func (l *MultiLogger) PlainStdout(args ...interface{}) {
 
 	for _, n := range l.allFiles() {
 		if n != nil {
-			l.writeOutput(n.File, b.Bytes())
+			l.writeOutput(n.output(), b.Bytes())
 		}
 	}
 }
@@ -1253,4 +1365,7 @@ var DefaultLogger = New(
 
 func init() {
 	//log.SetFlags(log.LstdFlags | log.Llongfile)
+
+	// it writes to stdout, but lives as long as the process, so it is listed by the admin handler and signals.Dump
+	shared.RegisterLogger(DefaultLogger)
 }
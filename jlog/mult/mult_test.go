@@ -2,12 +2,21 @@ package mult
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
+	jctx "github.com/oresoftware/json-logging/jlog/ctx"
 	ll "github.com/oresoftware/json-logging/jlog/level"
+	"github.com/oresoftware/json-logging/jlog/sample"
+	"github.com/oresoftware/json-logging/jlog/shared"
+	"github.com/oresoftware/json-logging/jlog/writer"
+	"github.com/oresoftware/json-logging/test/callsite"
 )
 
 func decodeJSONLines(t *testing.T, raw []byte) [][]interface{} {
@@ -111,3 +120,400 @@ func TestMultiLoggerJSONArrayFormatAndFileLevels(t *testing.T) {
 		t.Fatalf("unexpected info output levels: %#v then %#v", infoRecords[0][2], infoRecords[1][2])
 	}
 }
+
+func TestMultiLoggerAddOutputAcceptsAnyWriter(t *testing.T) {
+	var buf bytes.Buffer
+
+	log := New("multi-writer", "", []*FileLevel{{
+		Level:  ll.ERROR,
+		Writer: &buf,
+		IsJSON: true,
+	}})
+	var infoBuf bytes.Buffer
+	log.AddOutput(ll.INFO, &infoBuf)
+
+	log.Info("info only")
+	log.Error("both")
+
+	if records := decodeJSONLines(t, buf.Bytes()); len(records) != 1 || records[0][2] != "ERROR" {
+		t.Fatalf("unexpected error output records: %#v", records)
+	}
+	if records := decodeJSONLines(t, infoBuf.Bytes()); len(records) != 2 {
+		t.Fatalf("expected two records in the info output, got %d", len(records))
+	}
+}
+
+func TestMultiLoggerFlushAndClose(t *testing.T) {
+	f := tempLogFile(t)
+
+	log := New("multi-lifecycle", "", []*FileLevel{{
+		Level:  ll.INFO,
+		File:   f,
+		IsJSON: true,
+	}}).SetAsync(writer.AsyncParams{Capacity: 4}).SetFlushOnCritical(true)
+
+	for i := 0; i < 20; i++ {
+		log.Info("queued", i)
+	}
+	log.Critical("crash")
+
+	// FlushOnCritical means everything is on disk before Critical returns
+	raw, err := os.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if records := decodeJSONLines(t, raw); len(records) != 21 || records[20][2] != "CRITICAL" {
+		t.Fatalf("expected 21 records ending with CRITICAL, got %d", len(records))
+	}
+
+	if err := log.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte("x")); err == nil {
+		t.Fatal("expected the output file to be closed")
+	}
+	for _, z := range shared.RegisteredLoggers() {
+		if z == log {
+			t.Fatal("expected a closed logger to be unregistered")
+		}
+	}
+}
+
+func TestMultiLoggerAsyncWithChildrenAndLock(t *testing.T) {
+	var buf bytes.Buffer
+
+	log := New("multi-async-lock", "", []*FileLevel{{Level: ll.INFO, Writer: &buf, IsJSON: true}})
+	child := log.Child(&map[string]interface{}{"child": true})
+
+	// the children share the outputs, so switching them must not race with their writes
+	var done = make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 50; i++ {
+			child.Info("child", i)
+		}
+	}()
+	log.SetAsync(writer.AsyncParams{Capacity: 8})
+	<-done
+
+	locked, unlock := log.NewLoggerWithLock()
+	if _, ok := locked.Files[0].output().(*writer.AsyncWriter); ok {
+		t.Fatal("expected the locked logger to skip the async queue")
+	}
+	locked.Info("locked")
+	unlock()
+
+	if err := log.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	records := decodeJSONLines(t, buf.Bytes())
+	if len(records) != 51 || records[50][7].([]interface{})[0] != "locked" {
+		t.Fatalf("expected the 50 child records before the locked one, got %d", len(records))
+	}
+}
+
+func TestMultiLoggerSetFileLevelReachesChildren(t *testing.T) {
+	var buf bytes.Buffer
+
+	log := New("multi-file-level", "", []*FileLevel{{Level: ll.WARN, Writer: &buf, IsJSON: true}})
+	child := log.Named("worker")
+
+	// the children share the FileLevels, so changing a threshold must not race with their writes
+	var done = make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 50; i++ {
+			child.Debug("child", i)
+		}
+	}()
+	log.SetFileLevel(0, ll.INFO)
+	<-done
+
+	log.SetFileLevel(0, ll.DEBUG)
+	child.Debug("shown")
+	if level, ok := child.FileLevelAt(0); !ok || level != ll.DEBUG || !child.IsDebugEnabled() {
+		t.Fatalf("expected the child to get the new threshold: %v %v", level, ok)
+	}
+	if _, ok := log.FileLevelAt(1); ok {
+		t.Fatal("expected no output at index 1")
+	}
+
+	records := decodeJSONLines(t, buf.Bytes())
+	if len(records) < 1 || records[len(records)-1][7].([]interface{})[0] != "shown" {
+		t.Fatalf("unexpected records: %#v", records)
+	}
+}
+
+func TestMultiLoggerShowCaller(t *testing.T) {
+	var buf bytes.Buffer
+
+	log := New("caller-mult", "", []*FileLevel{{Level: ll.TRACE, Writer: &buf, IsJSON: true}}).SetShowCaller(true)
+	callsite.Call(func() { log.Warn("here") })
+	callsite.Call(func() { log.WarnF("%s", "here") })
+
+	for _, r := range decodeJSONLines(t, buf.Bytes()) {
+		caller, ok := r[6].(map[string]interface{})["caller"].(map[string]interface{})
+		if !ok || !strings.HasSuffix(caller["file"].(string), "callsite.go") {
+			t.Fatalf("expected the caller to be the call site: %#v", r[6])
+		}
+	}
+}
+
+func TestMultiLoggerErrorIdAndOpts(t *testing.T) {
+	var buf bytes.Buffer
+
+	log := New("opts-mult", "", []*FileLevel{{Level: ll.TRACE, Writer: &buf, IsJSON: true}})
+	log.Info("with error id", ErrId("E42"), Opts{IsPrintStackTrace: true})
+	log.Critical("no stack", &Opts{IsSkipStackTrace: true, IsMustFlush: true})
+
+	records := decodeJSONLines(t, buf.Bytes())
+	if len(records) != 2 {
+		t.Fatalf("expected two records, got %d", len(records))
+	}
+	if records[0][6].(map[string]interface{})["error_id"] != "E42" {
+		t.Fatalf("expected an error_id in the meta: %#v", records[0][6])
+	}
+	if args := records[0][7].([]interface{}); len(args) != 2 || args[1].(map[string]interface{})["Frames"] == nil {
+		t.Fatalf("expected a forced stack trace: %#v", args)
+	}
+	if args := records[1][7].([]interface{}); len(args) != 1 {
+		t.Fatalf("expected no stack trace: %#v", args)
+	}
+}
+
+type panicValue struct {
+	Code   int
+	secret string
+}
+
+func TestMultiLoggerRecover(t *testing.T) {
+	var buf bytes.Buffer
+
+	log := New("recover-mult", "", []*FileLevel{{Level: ll.TRACE, Writer: &buf, IsJSON: true}})
+
+	done := make(chan struct{})
+	log.Go(func() {
+		defer close(done)
+		panic(panicValue{Code: 7, secret: "x"})
+	})
+	<-done
+
+	var code = 0
+	exit = func(c int) { code = c }
+	defer func() { exit = os.Exit }()
+	func() {
+		defer log.SetPanicParams(PanicParams{ExitCode: 2}).Recover()
+		panic("exit")
+	}()
+
+	records := decodeJSONLines(t, buf.Bytes())
+	if len(records) != 2 || code != 2 {
+		t.Fatalf("expected two records and exit code 2, got %d records and %d", len(records), code)
+	}
+	for _, r := range records {
+		if r[2] != "CRITICAL" || r[6].(map[string]interface{})["panic"] != true || len(r[7].([]interface{})) != 3 {
+			t.Fatalf("unexpected panic record: %#v", r)
+		}
+	}
+	// inspected like the lib logger does, rather than marshalled as {}
+	if v := records[0][7].([]interface{})[1]; !strings.Contains(fmt.Sprint(v), "secret") {
+		t.Fatalf("expected the panic value to be inspected: %#v", v)
+	}
+}
+
+func TestMultiLoggerPanicRecordsAreNotSampledOrDeduped(t *testing.T) {
+	var buf bytes.Buffer
+	log := New("recover-sampled-mult", "", []*FileLevel{{Level: ll.TRACE, Writer: &buf, IsJSON: true}}).
+		SetSampling(SampleParams{Levels: map[ll.LogLevel]SampleRule{ll.CRITICAL: {First: 1}}, KeyBy: sample.KeyByLevel}).
+		SetDedupWindow(time.Minute)
+
+	log.Critical("uses up the sampling rule")
+	for i := 0; i < 2; i++ {
+		func() {
+			defer log.Recover()
+			panic("the same panic")
+		}()
+	}
+
+	var panics = 0
+	for _, r := range decodeJSONLines(t, buf.Bytes()) {
+		if r[6].(map[string]interface{})["panic"] == true {
+			panics++
+		}
+	}
+	if panics != 2 {
+		t.Fatalf("expected both panics to be logged, got %d", panics)
+	}
+}
+
+func TestMultiLoggerDedup(t *testing.T) {
+	var buf bytes.Buffer
+
+	log := New("dedup-mult", "", []*FileLevel{{Level: ll.TRACE, Writer: &buf, IsJSON: true}}).SetDedupWindow(time.Minute)
+	log.Warn("retrying")
+	log.WarnF("%s", "retrying")
+	log.WarnF("%s", "retrying")
+	log.Flush(context.Background())
+
+	records := decodeJSONLines(t, buf.Bytes())
+	if len(records) != 2 || !strings.HasPrefix(records[1][7].([]interface{})[0].(string), "previous message repeated 2 times") {
+		t.Fatalf("unexpected records: %#v", records)
+	}
+}
+
+func TestMultiLoggerCustomLevels(t *testing.T) {
+	notice, err := ll.Register(ll.LevelInfo{Severity: 35, Name: "NOTICE"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var all, important bytes.Buffer
+	log := New("custom-mult", "", []*FileLevel{
+		{Level: ll.TRACE, Writer: &all, IsJSON: true},
+		{Level: notice, Writer: &important, IsJSON: true},
+	})
+	log.Info("routine")
+	log.Log(notice, "notable")
+
+	if records := decodeJSONLines(t, all.Bytes()); len(records) != 2 || records[1][2] != "NOTICE" {
+		t.Fatalf("unexpected records: %#v", records)
+	}
+	if records := decodeJSONLines(t, important.Bytes()); len(records) != 1 || records[0][2] != "NOTICE" {
+		t.Fatalf("unexpected records: %#v", records)
+	}
+}
+
+func TestMultiLoggerNamedLevels(t *testing.T) {
+	SetNamedLevel("test-http", ll.WARN)
+	defer ClearNamedLevel("test-http")
+
+	var buf bytes.Buffer
+	log := New("named-mult", "", []*FileLevel{{Level: ll.DEBUG, Writer: &buf, IsJSON: true}})
+	client := log.Named("test-http").Named("client")
+
+	client.Info("hidden")
+	client.Error("shown")
+	log.Debug("not named")
+
+	records := decodeJSONLines(t, buf.Bytes())
+	if len(records) != 2 || records[0][6].(map[string]interface{})["logger"] != "test-http.client" {
+		t.Fatalf("unexpected records: %#v", records)
+	}
+	if client.EffectiveLevel() != ll.WARN || log.EffectiveLevel() != ll.DEBUG {
+		t.Fatalf("unexpected levels: %v %v", client.EffectiveLevel(), log.EffectiveLevel())
+	}
+}
+
+func TestMultiLoggerNamedLevelsOverrideFileLevels(t *testing.T) {
+	SetNamedLevel("test-db", ll.TRACE)
+	defer ClearNamedLevel("test-db")
+
+	var info, errs bytes.Buffer
+	log := New("named-override", "", []*FileLevel{
+		{Level: ll.INFO, Writer: &info, IsJSON: true},
+		{Level: ll.ERROR, Writer: &errs, IsJSON: true},
+	})
+	db := log.Named("test-db")
+
+	// more verbose than the files, for this logger only
+	db.Trace("shown")
+	log.Debug("hidden")
+
+	if records := decodeJSONLines(t, info.Bytes()); len(records) != 1 || records[0][2] != "TRACE" {
+		t.Fatalf("unexpected records: %#v", records)
+	}
+	if records := decodeJSONLines(t, errs.Bytes()); len(records) != 1 {
+		t.Fatalf("unexpected records: %#v", records)
+	}
+	if !db.IsTraceEnabled() || log.IsDebugEnabled() || db.EffectiveLevel() != ll.TRACE || log.EffectiveLevel() != ll.INFO {
+		t.Fatalf("unexpected levels: %v %v", db.EffectiveLevel(), log.EffectiveLevel())
+	}
+}
+
+func TestMultiLoggerCtxMethodsExtractContextFields(t *testing.T) {
+	var buf bytes.Buffer
+	log := New("ctx-mult", "", []*FileLevel{{Level: ll.INFO, Writer: &buf, IsJSON: true}})
+
+	ctx := jctx.WithRequestId(context.Background(), "req-5")
+	ctx = jctx.WithMetaPairs(ctx, "tenant", "acme")
+	ctx = log.TagPair("component", "worker").WithContext(ctx)
+
+	FromContext(ctx).DebugCtx(ctx, "hidden")
+	FromContext(ctx).WarnCtx(ctx, MP("tenant", "override"), "handled")
+
+	if m := *jctx.MetaFields(jctx.WithMetaPairs(nil, "a", 1)).Map(); m["a"] != 1 {
+		t.Fatalf("unexpected meta from a nil ctx: %#v", m)
+	}
+	if FromContext(context.Background()) != DefaultLogger {
+		t.Fatal("expected the default logger for a context without one")
+	}
+
+	records := decodeJSONLines(t, buf.Bytes())
+	if len(records) != 1 {
+		t.Fatalf("expected one record, got %d", len(records))
+	}
+	meta := records[0][6].(map[string]interface{})
+	if meta["request_id"] != "req-5" || meta["component"] != "worker" || meta["tenant"] != "override" {
+		t.Fatalf("unexpected metadata: %#v", meta)
+	}
+}
+
+func TestMultiLoggerStackTraceLevelDefaultsToErrorWhenUnset(t *testing.T) {
+	var buf bytes.Buffer
+
+	hasStack := func(r []interface{}) bool {
+		args := r[7].([]interface{})
+		st, ok := args[len(args)-1].(map[string]interface{})
+		return ok && st["Frames"] != nil
+	}
+
+	// StackTraceLevel is the zero value, as it is for a logger built without a constructor
+	log := New("stack-unset-mult", "", []*FileLevel{{Level: ll.TRACE, Writer: &buf, IsJSON: true}})
+	log.StackTraceLevel = ll.TRACE
+	log.Warn("no stack")
+	log.Error("stack")
+	log.SetStackTraceLevel(ll.TRACE).Info("stack on every record once set")
+
+	fromParams := NewMultiLogger(MultLoggerParams{
+		AppName:         "stack-params-mult",
+		Files:           []*FileLevel{{Level: ll.TRACE, Writer: &buf, IsJSON: true}},
+		StackTraceLevel: ll.WARN,
+	})
+	fromParams.Info("no stack")
+	fromParams.Child(&MF{}).Warn("the child keeps it")
+
+	var got []bool
+	for _, r := range decodeJSONLines(t, buf.Bytes()) {
+		got = append(got, hasStack(r))
+	}
+	if fmt.Sprint(got) != "[false true true false true]" {
+		t.Fatalf("unexpected stacks: %v", got)
+	}
+}
+
+func TestMultiLoggerChildCloseLeavesTheParentOutputsOpen(t *testing.T) {
+	f := tempLogFile(t)
+	log := New("close-parent-mult", "", []*FileLevel{{Level: ll.INFO, File: f, IsJSON: true}})
+	defer log.Close()
+
+	// the child closes the output added to it, not the one it shares
+	own := tempLogFile(t)
+	child := log.TagPair("request_id", "abc").AddOutputFile(ll.INFO, own)
+	if err := child.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := own.Write([]byte("x")); err == nil {
+		t.Fatal("expected the child's own output to be closed")
+	}
+
+	locked, unlock := log.NewLoggerWithLock()
+	if err := locked.Close(); err != nil {
+		t.Fatal(err)
+	}
+	unlock()
+	log.Info("still open")
+
+	if records := decodeJSONLines(t, readLogFile(t, f)); len(records) != 1 {
+		t.Fatalf("expected the parent to keep writing, got %d records", len(records))
+	}
+}
@@ -0,0 +1,65 @@
+package mult
+
+import (
+	"os"
+
+	hlpr "github.com/oresoftware/json-logging/jlog/helper"
+	ll "github.com/oresoftware/json-logging/jlog/level"
+)
+
+// exit is replaced in tests
+var exit = os.Exit
+
+// SetPanicParams says what Recover/Go do after logging a panic.
+func (l *MultiLogger) SetPanicParams(p PanicParams) *MultiLogger {
+	l.Mtx.Lock()
+	defer l.Mtx.Unlock()
+	l.PanicParams = p
+	return l
+}
+
+// Recover logs a panic at CRITICAL, with the stack of the panicking goroutine and the panic value
+// (errors are expanded, like any other arg), then swallows it, panics again or exits (see PanicParams).
+// It has to be deferred directly, eg defer log.Recover()
+func (l *MultiLogger) Recover() {
+	if r := recover(); r != nil {
+		l.onPanic(r)
+	}
+}
+
+// Go runs fn in a new goroutine which recovers (and logs) panics, see Recover.
+func (l *MultiLogger) Go(fn func()) {
+	go func() {
+		defer l.Recover()
+		fn()
+	}()
+}
+
+func (l *MultiLogger) onPanic(r interface{}) {
+	l.Mtx.RLock()
+	p := l.PanicParams
+	l.Mtx.RUnlock()
+
+	// written directly rather than with Critical, so that sampling and dedup can not swallow it
+	if l.IsLevelEnabled(ll.CRITICAL) {
+		var args = []interface{}{"panic:", Inspect(r), MP("panic", true), Opts{IsPrintStackTrace: true, IsMustFlush: true}}
+		var meta, newArgs, opts = l.getMetaFields(&args)
+		newArgs = l.withStackTrace(ll.CRITICAL, newArgs, opts)
+		l.writeJSON(ll.CRITICAL, meta, &newArgs)
+		l.flushAfter(ll.CRITICAL, opts)
+	}
+
+	if p.ExitCode != 0 {
+		exit(p.ExitCode)
+		return
+	}
+	if p.IsRePanic {
+		panic(r)
+	}
+}
+
+// Inspect converts v the way pretty output does, with reflection, so that values such as
+// structs with unexported fields can be logged as JSON.
+func Inspect(v interface{}) interface{} {
+	return hlpr.Inspect(v)
+}
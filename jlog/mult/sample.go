@@ -0,0 +1,54 @@
+package mult
+
+import (
+	ll "github.com/oresoftware/json-logging/jlog/level"
+	"github.com/oresoftware/json-logging/jlog/sample"
+)
+
+// SetSampling drops records according to p, and logs how many were dropped every p.SummaryInterval.
+// Params without any Levels turn sampling off.
+func (l *MultiLogger) SetSampling(p SampleParams) *MultiLogger {
+	var s *sample.Sampler
+	if len(p.Levels) > 0 {
+		s = sample.NewSampler(p, logSampleSummary)
+	}
+
+	l.Mtx.Lock()
+	old := l.Sampler
+	l.Sampler = s
+	l.Mtx.Unlock()
+
+	// do not lose the count of the old sampler
+	old.Flush()
+	return l
+}
+
+// isSampledOut is true if sampling drops this record, template is used when keying by message,
+// and skip (Opts.SkipFrames) when keying by call site
+func (l *MultiLogger) isSampledOut(level ll.LogLevel, template string, skip int) bool {
+	l.Mtx.RLock()
+	s := l.Sampler
+	l.Mtx.RUnlock()
+	return !s.Allow(l, level, template, skip)
+}
+
+func (l *MultiLogger) flushSamples() {
+	l.Mtx.RLock()
+	s := l.Sampler
+	l.Mtx.RUnlock()
+	s.Flush()
+}
+
+// the summary is written directly, so that it is not sampled itself, and through the logger
+// (or child) the records came from, so that it has its meta fields
+func logSampleSummary(s sample.Summary) {
+	l, ok := s.Origin.(*MultiLogger)
+	if !ok {
+		return
+	}
+	var empty []interface{}
+	var meta, _, _ = l.getMetaFields(&empty)
+	(*meta.Map())["sampled"] = s.Meta()
+	var args = []interface{}{s.Message()}
+	l.writeSwitch(s.Level, meta, &args)
+}
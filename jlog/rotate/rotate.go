@@ -0,0 +1,361 @@
+package rotate
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const backupTimeFormat = "2006-01-02T15-04-05.000000000"
+
+type RotatingFileParams struct {
+	Filename   string
+	MaxSize    int64         // bytes, 0 means no size based rotation
+	Interval   time.Duration // eg time.Hour, rotates on UTC boundaries, 0 means no time based rotation
+	MaxBackups int           // 0 means keep all backups
+	Compress   bool          // gzip rotated files
+	ReopenOn   []os.Signal   // eg syscall.SIGHUP, for logrotate compatibility
+}
+
+// RotatingFile is an io.Writer which can be used as a Logger output or a FileLevel Writer.
+// It exposes Fd(), so the writer package shares the per-inode lock with any other handle to the same file.
+type RotatingFile struct {
+	mtx          sync.Mutex
+	p            RotatingFileParams
+	file         *os.File
+	size         int64
+	nextRotation time.Time
+	bg           sync.WaitGroup
+	bgMtx        sync.Mutex // serializes compression and cleanup
+	sigs         chan os.Signal
+	done         chan struct{}
+}
+
+func NewRotatingFile(p RotatingFileParams) (*RotatingFile, error) {
+	if p.Filename == "" {
+		return nil, errors.New("json-logging: rotating file needs a filename")
+	}
+
+	fp, err := filepath.Abs(p.Filename)
+	if err != nil {
+		return nil, err
+	}
+	p.Filename = fp
+
+	var r = &RotatingFile{p: p, done: make(chan struct{})}
+
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+
+	if len(p.ReopenOn) > 0 {
+		r.sigs = make(chan os.Signal, 1)
+		signal.Notify(r.sigs, p.ReopenOn...)
+		go r.handleSignals()
+	}
+
+	return r, nil
+}
+
+// must hold r.mtx
+func (r *RotatingFile) open() error {
+	return r.openPath(r.p.Filename)
+}
+
+// must hold r.mtx, r.file is only replaced if the file could be opened
+func (r *RotatingFile) openPath(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	r.file = f
+	r.size = info.Size()
+
+	if r.p.Interval > 0 {
+		r.nextRotation = time.Now().UTC().Truncate(r.p.Interval).Add(r.p.Interval)
+	}
+
+	return nil
+}
+
+func (r *RotatingFile) handleSignals() {
+	for {
+		select {
+		case <-r.done:
+			return
+		case <-r.sigs:
+			if err := r.Reopen(); err != nil {
+				fmt.Fprintln(os.Stderr, "json-logging: could not reopen log file:", err)
+			}
+		}
+	}
+}
+
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if r.file == nil {
+		return 0, os.ErrClosed
+	}
+
+	// a failed rotation keeps the current file, so the record is still written
+	if r.shouldRotate(int64(len(p))) {
+		if err := r.rotate(); err != nil {
+			fmt.Fprintln(os.Stderr, "json-logging: could not rotate log file:", err)
+		}
+	}
+	if r.file == nil {
+		return 0, os.ErrClosed
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// must hold r.mtx
+func (r *RotatingFile) shouldRotate(incoming int64) bool {
+	if r.p.Interval > 0 && !time.Now().Before(r.nextRotation) {
+		return true
+	}
+	// never rotate an empty file, a single record bigger than MaxSize still gets written
+	return r.p.MaxSize > 0 && r.size > 0 && r.size+incoming > r.p.MaxSize
+}
+
+// Rotate closes the current file, renames it to a timestamped backup and opens a new one.
+func (r *RotatingFile) Rotate() error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if r.file == nil {
+		return os.ErrClosed
+	}
+	return r.rotate()
+}
+
+// must hold r.mtx, if anything fails the current file (under whichever name it has) is reopened
+func (r *RotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+	r.file = nil
+
+	backup := r.nextBackupName(time.Now().UTC())
+	if err := os.Rename(r.p.Filename, backup); err != nil && !os.IsNotExist(err) {
+		if oerr := r.open(); oerr != nil {
+			return errors.Join(err, oerr)
+		}
+		return err
+	}
+
+	if err := r.open(); err != nil {
+		if oerr := r.openPath(backup); oerr != nil {
+			return errors.Join(err, oerr)
+		}
+		return err
+	}
+
+	r.bg.Add(1)
+	go func() {
+		defer r.bg.Done()
+		r.postRotate(backup)
+	}()
+
+	return nil
+}
+
+// Reopen reopens the file by path, after logrotate has moved it for example.
+// The new file is opened before the old one is closed, so a failure keeps the old one.
+func (r *RotatingFile) Reopen() error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if r.file == nil {
+		return os.ErrClosed
+	}
+	var old = r.file
+	if err := r.open(); err != nil {
+		return err
+	}
+	return old.Close()
+}
+
+func (r *RotatingFile) backupName(t time.Time) string {
+	dir := filepath.Dir(r.p.Filename)
+	ext := filepath.Ext(r.p.Filename)
+	prefix := strings.TrimSuffix(filepath.Base(r.p.Filename), ext)
+	return filepath.Join(dir, fmt.Sprintf("%s-%s%s", prefix, t.Format(backupTimeFormat), ext))
+}
+
+// the name is bumped if a backup already exists with the same timestamp
+func (r *RotatingFile) nextBackupName(t time.Time) string {
+	for {
+		name := r.backupName(t)
+		if _, err := os.Stat(name); os.IsNotExist(err) {
+			if _, err := os.Stat(name + ".gz"); os.IsNotExist(err) {
+				return name
+			}
+		}
+		t = t.Add(time.Nanosecond)
+	}
+}
+
+func (r *RotatingFile) postRotate(backup string) {
+	r.bgMtx.Lock()
+	defer r.bgMtx.Unlock()
+	if r.p.Compress {
+		if err := compressFile(backup); err != nil {
+			fmt.Fprintln(os.Stderr, "json-logging: could not compress rotated log file:", err)
+		}
+	}
+	if err := r.removeOldBackups(); err != nil {
+		fmt.Fprintln(os.Stderr, "json-logging: could not remove old log files:", err)
+	}
+}
+
+// Backups lists the rotated files, oldest first. A .gz which is still being written next to its
+// uncompressed file is left out, so each backup is listed once.
+func (r *RotatingFile) Backups() ([]string, error) {
+	dir := filepath.Dir(r.p.Filename)
+	ext := filepath.Ext(r.p.Filename)
+	prefix := strings.TrimSuffix(filepath.Base(r.p.Filename), ext) + "-"
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var names = map[string]bool{}
+	for _, e := range entries {
+		if !e.IsDir() {
+			names[e.Name()] = true
+		}
+	}
+
+	var backups []string
+	for name := range names {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		if strings.HasSuffix(name, ".gz") && names[strings.TrimSuffix(name, ".gz")] {
+			continue
+		}
+		ts := strings.TrimSuffix(strings.TrimSuffix(strings.TrimPrefix(name, prefix), ".gz"), ext)
+		if _, err := time.Parse(backupTimeFormat, ts); err != nil {
+			continue
+		}
+		backups = append(backups, filepath.Join(dir, name))
+	}
+
+	// the timestamp format sorts lexically
+	sort.Strings(backups)
+	return backups, nil
+}
+
+// must hold r.bgMtx, so that a file being compressed is not removed
+func (r *RotatingFile) removeOldBackups() error {
+	if r.p.MaxBackups < 1 {
+		return nil
+	}
+
+	backups, err := r.Backups()
+	if err != nil {
+		return err
+	}
+
+	for len(backups) > r.p.MaxBackups {
+		if err := os.Remove(backups[0]); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		backups = backups[1:]
+	}
+
+	return nil
+}
+
+func compressFile(src string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(src+".gz", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(out)
+	if _, err := io.Copy(gz, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+
+	return os.Remove(src)
+}
+
+// Name is the path of the current file.
+func (r *RotatingFile) Name() string {
+	return r.p.Filename
+}
+
+// Fd returns the descriptor of the current file, used by the writer package for inode based locking.
+func (r *RotatingFile) Fd() uintptr {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if r.file == nil {
+		return ^uintptr(0)
+	}
+	return r.file.Fd()
+}
+
+func (r *RotatingFile) Sync() error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if r.file == nil {
+		return nil
+	}
+	return r.file.Sync()
+}
+
+// Close closes the file and waits for any pending compression/cleanup.
+func (r *RotatingFile) Close() error {
+	r.mtx.Lock()
+	var err error
+	if r.file != nil {
+		err = r.file.Close()
+		r.file = nil
+		if r.sigs != nil {
+			signal.Stop(r.sigs)
+			close(r.done)
+		}
+	}
+	r.mtx.Unlock()
+	r.bg.Wait()
+	return err
+}
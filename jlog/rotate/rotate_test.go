@@ -0,0 +1,288 @@
+package rotate
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/oresoftware/json-logging/jlog/writer"
+)
+
+func TestRotatesOnSizeAndKeepsBackups(t *testing.T) {
+	fp := filepath.Join(t.TempDir(), "app.log")
+
+	r, err := NewRotatingFile(RotatingFileParams{
+		Filename:   fp,
+		MaxSize:    64,
+		MaxBackups: 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	line := []byte(strings.Repeat("x", 39) + "\n")
+	for i := 0; i < 5; i++ {
+		if _, err := writer.Write(r, line); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	backups, err := r.Backups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 2 {
+		t.Fatalf("expected two backups to be kept, got %v", backups)
+	}
+
+	raw, err := os.ReadFile(fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(raw, line) {
+		t.Fatalf("expected the current file to hold the last line only, got %q", raw)
+	}
+}
+
+func TestCompressesRotatedFiles(t *testing.T) {
+	fp := filepath.Join(t.TempDir(), "app.log")
+
+	r, err := NewRotatingFile(RotatingFileParams{Filename: fp, Compress: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := r.Write([]byte("first\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	backups, err := r.Backups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 1 || !strings.HasSuffix(backups[0], ".log.gz") {
+		t.Fatalf("expected one gzipped backup, got %v", backups)
+	}
+
+	f, err := os.Open(backups[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(raw) != "first\n" {
+		t.Fatalf("unexpected backup contents: %q", raw)
+	}
+}
+
+func TestConcurrentWritersStayLineAtomic(t *testing.T) {
+	fp := filepath.Join(t.TempDir(), "app.log")
+
+	r, err := NewRotatingFile(RotatingFileParams{Filename: fp, MaxSize: 4096})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	line := strings.Repeat("y", 99) + "\n"
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				writer.WriteString(r, line)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	backups, err := r.Backups()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	total := 0
+	for _, p := range append(backups, fp) {
+		raw, err := os.ReadFile(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, l := range strings.SplitAfter(string(raw), "\n") {
+			if l == "" {
+				continue
+			}
+			if l != line {
+				t.Fatalf("found an interleaved line in %s: %q", p, l)
+			}
+			total++
+		}
+	}
+
+	if total != 400 {
+		t.Fatalf("expected 400 lines across all files, got %d", total)
+	}
+}
+
+func TestRotatesOnInterval(t *testing.T) {
+	fp := filepath.Join(t.TempDir(), "app.log")
+
+	r, err := NewRotatingFile(RotatingFileParams{Filename: fp, Interval: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := r.Write([]byte("first\n")); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if _, err := r.Write([]byte("second\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	backups, err := r.Backups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 1 {
+		t.Fatalf("expected one backup after the interval, got %v", backups)
+	}
+
+	raw, err := os.ReadFile(backups[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(raw) != "first\n" {
+		t.Fatalf("expected the backup to hold the first line, got %q", raw)
+	}
+
+	raw, err = os.ReadFile(fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(raw) != "second\n" {
+		t.Fatalf("expected the current file to hold the second line, got %q", raw)
+	}
+}
+
+func TestReopensOnSignal(t *testing.T) {
+	dir := t.TempDir()
+	fp := filepath.Join(dir, "app.log")
+
+	r, err := NewRotatingFile(RotatingFileParams{Filename: fp, ReopenOn: []os.Signal{syscall.SIGHUP}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	if _, err := r.Write([]byte("before\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	// what logrotate does before sending the signal
+	moved := filepath.Join(dir, "app.log.1")
+	if err := os.Rename(fp, moved); err != nil {
+		t.Fatal(err)
+	}
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, err := os.Stat(fp); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the file to be reopened by path after the signal")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if _, err := r.Write([]byte("after\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := os.ReadFile(moved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(raw) != "before\n" {
+		t.Fatalf("expected the moved file to hold the line from before the signal, got %q", raw)
+	}
+
+	raw, err = os.ReadFile(fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(raw) != "after\n" {
+		t.Fatalf("expected the reopened file to hold the line from after the signal, got %q", raw)
+	}
+}
+
+func TestFailedReopenKeepsTheOldFile(t *testing.T) {
+	dir := t.TempDir()
+	fp := filepath.Join(dir, "app.log")
+
+	r, err := NewRotatingFile(RotatingFileParams{Filename: fp})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	// a directory in place of the file makes the open fail
+	moved := filepath.Join(dir, "app.log.1")
+	if err := os.Rename(fp, moved); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(fp, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.Reopen(); err == nil {
+		t.Fatal("expected reopening onto a directory to fail")
+	}
+	if _, err := r.Write([]byte("still logging\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := os.ReadFile(moved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(raw) != "still logging\n" {
+		t.Fatalf("expected the old file to still be written, got %q", raw)
+	}
+}
@@ -0,0 +1,299 @@
+package sample
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	hlpr "github.com/oresoftware/json-logging/jlog/helper"
+	ll "github.com/oresoftware/json-logging/jlog/level"
+)
+
+// KeyBy says which records are counted together by Rule.First/Thereafter
+type KeyBy int
+
+const (
+	KeyByCallSite KeyBy = iota // the file:line of the logging call
+	KeyByMessage               // the first string arg, or the format of the F variants
+	KeyByLevel                 // all the records of a level
+)
+
+// the max number of keys in a summary record
+const maxSummaryKeys = 20
+
+// counters are pruned when there are more keys than this, eg when keying by messages with ids in them
+const maxKeys = 10000
+
+// Rule limits the records of one level.
+type Rule struct {
+	First      int     // the first N records of a key per Interval are kept, 0 with Thereafter 0 keeps them all
+	Thereafter int     // after the first N, every Mth record is kept, 0 drops the rest of the Interval
+	Rate       float64 // token bucket for the whole level, records per second, 0 means no limit
+	Burst      int     // the size of the token bucket, 1 if 0
+}
+
+type Params struct {
+	Levels          map[ll.LogLevel]Rule // levels without a rule are never sampled
+	Interval        time.Duration        // the window of Rule.First/Thereafter, 1s if 0
+	KeyBy           KeyBy                // KeyByCallSite by default
+	SummaryInterval time.Duration        // how often suppressed records are reported, 10s if 0
+}
+
+// Summary reports the records of one origin which were suppressed since the last summary.
+type Summary struct {
+	Origin     interface{} // the logger the records were logged with, see Sampler.Allow
+	Since      time.Time
+	Suppressed int
+	Level      ll.LogLevel // the highest level of the suppressed records
+	Keys       []KeyCount  // the most suppressed keys first
+}
+
+type KeyCount struct {
+	Level string `json:"level"`
+	Key   string `json:"key"`
+	Count int    `json:"count"`
+}
+
+type counterKey struct {
+	level ll.LogLevel
+	key   string
+}
+
+type suppressedKey struct {
+	origin interface{}
+	counterKey
+}
+
+type counter struct {
+	start time.Time
+	n     int
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Sampler decides which records are kept, it is shared by a logger and its children.
+type Sampler struct {
+	mtx        sync.Mutex
+	p          Params
+	onSummary  func(Summary)
+	counters   map[counterKey]*counter
+	buckets    map[ll.LogLevel]*bucket
+	suppressed map[suppressedKey]int
+	origins    []interface{} // in the order of their first suppressed record
+	since      time.Time
+	timer      *time.Timer
+	now        func() time.Time
+}
+
+// NewSampler creates a sampler, onSummary is called (from a timer goroutine) with the suppressed
+// records every SummaryInterval, as long as there are any, once per origin.
+func NewSampler(p Params, onSummary func(Summary)) *Sampler {
+	var levels = map[ll.LogLevel]Rule{}
+	for k, v := range p.Levels {
+		levels[k] = v
+	}
+	p.Levels = levels
+
+	if p.Interval <= 0 {
+		p.Interval = time.Second
+	}
+	if p.SummaryInterval <= 0 {
+		p.SummaryInterval = 10 * time.Second
+	}
+
+	return &Sampler{
+		p:          p,
+		onSummary:  onSummary,
+		counters:   map[counterKey]*counter{},
+		buckets:    map[ll.LogLevel]*bucket{},
+		suppressed: map[suppressedKey]int{},
+		now:        time.Now,
+	}
+}
+
+// Template is the key of a record for KeyByMessage, the first string arg.
+func Template(args []interface{}) string {
+	for _, a := range args {
+		if s, ok := a.(string); ok {
+			return s
+		}
+	}
+	return ""
+}
+
+// Allow is true if the record should be logged, a nil sampler allows everything. The sampler is shared by
+// a logger and its children, origin is the one the record was logged with, its suppressed records are
+// reported in a summary of their own. template is only used with KeyByMessage, and skip (Opts.SkipFrames)
+// with KeyByCallSite.
+func (s *Sampler) Allow(origin interface{}, level ll.LogLevel, template string, skip int) bool {
+	if s == nil {
+		return true
+	}
+	rule, ok := s.p.Levels[level]
+	if !ok {
+		return true
+	}
+
+	var ck = counterKey{level: level}
+	switch s.p.KeyBy {
+	case KeyByCallSite:
+		if c := hlpr.GetCaller(skip); c != nil {
+			ck.key = c.String()
+		}
+	case KeyByMessage:
+		ck.key = template
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	var now = s.now()
+	if s.isAllowed(rule, ck, now) {
+		return true
+	}
+
+	var sk = suppressedKey{origin: origin, counterKey: ck}
+	if _, ok := s.suppressed[sk]; !ok && !s.hasOrigin(origin) {
+		s.origins = append(s.origins, origin)
+	}
+	s.suppressed[sk]++
+	if s.timer == nil {
+		s.since = now
+		s.timer = time.AfterFunc(s.p.SummaryInterval, s.Flush)
+	}
+	return false
+}
+
+func (s *Sampler) isAllowed(rule Rule, ck counterKey, now time.Time) bool {
+	if rule.First > 0 || rule.Thereafter > 0 {
+		c, ok := s.counters[ck]
+		if !ok || now.Sub(c.start) >= s.p.Interval {
+			if !ok && len(s.counters) >= maxKeys {
+				s.prune(now)
+			}
+			c = &counter{start: now}
+			s.counters[ck] = c
+		}
+		c.n++
+		if c.n > rule.First && (rule.Thereafter < 1 || (c.n-rule.First)%rule.Thereafter != 0) {
+			return false
+		}
+	}
+
+	if rule.Rate > 0 {
+		var burst = float64(rule.Burst)
+		if burst < 1 {
+			burst = 1
+		}
+		b, ok := s.buckets[ck.level]
+		if !ok {
+			b = &bucket{tokens: burst, last: now}
+			s.buckets[ck.level] = b
+		}
+		b.tokens += now.Sub(b.last).Seconds() * rule.Rate
+		if b.tokens > burst {
+			b.tokens = burst
+		}
+		b.last = now
+		if b.tokens < 1 {
+			return false
+		}
+		b.tokens--
+	}
+
+	return true
+}
+
+// must hold s.mtx
+func (s *Sampler) hasOrigin(origin interface{}) bool {
+	for _, o := range s.origins {
+		if o == origin {
+			return true
+		}
+	}
+	return false
+}
+
+// prune drops the counters of past intervals
+func (s *Sampler) prune(now time.Time) {
+	for k, c := range s.counters {
+		if now.Sub(c.start) >= s.p.Interval {
+			delete(s.counters, k)
+		}
+	}
+}
+
+// Flush reports the suppressed records now, instead of waiting for the SummaryInterval.
+// A nil sampler has nothing to report.
+func (s *Sampler) Flush() {
+	if s == nil {
+		return
+	}
+
+	s.mtx.Lock()
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	if len(s.suppressed) < 1 {
+		s.mtx.Unlock()
+		return
+	}
+
+	var summaries = make([]*Summary, len(s.origins))
+	for i, o := range s.origins {
+		summaries[i] = &Summary{Origin: o, Since: s.since}
+	}
+	for k, n := range s.suppressed {
+		var summary *Summary
+		for _, x := range summaries {
+			if x.Origin == k.origin {
+				summary = x
+			}
+		}
+		if summary.Suppressed == 0 || !summary.Level.AtLeast(k.level) {
+			summary.Level = k.level
+		}
+		summary.Suppressed += n
+		summary.Keys = append(summary.Keys, KeyCount{Level: k.level.String(), Key: k.key, Count: n})
+	}
+	s.suppressed = map[suppressedKey]int{}
+	s.origins = nil
+	s.prune(s.now())
+	s.mtx.Unlock()
+
+	for _, summary := range summaries {
+		sort.Slice(summary.Keys, func(i, j int) bool {
+			if summary.Keys[i].Count != summary.Keys[j].Count {
+				return summary.Keys[i].Count > summary.Keys[j].Count
+			}
+			return summary.Keys[i].Key < summary.Keys[j].Key
+		})
+		if len(summary.Keys) > maxSummaryKeys {
+			summary.Keys = summary.Keys[:maxSummaryKeys]
+		}
+
+		if s.onSummary != nil {
+			s.onSummary(*summary)
+		}
+	}
+}
+
+// Meta is the "sampled" meta field of a summary record.
+func (x Summary) Meta() map[string]interface{} {
+	return map[string]interface{}{
+		"suppressed": x.Suppressed,
+		"since":      x.Since.UTC().Format("2006-01-02 15:04:05.000000"),
+		"keys":       x.Keys,
+	}
+}
+
+// Message is the text of a summary record.
+func (x Summary) Message() string {
+	return fmt.Sprintf("json-logging: %d records were suppressed by sampling since %s",
+		x.Suppressed, x.Since.UTC().Format("15:04:05"))
+}
@@ -0,0 +1,160 @@
+package sample
+
+import (
+	"testing"
+	"time"
+
+	ll "github.com/oresoftware/json-logging/jlog/level"
+	"github.com/oresoftware/json-logging/test/callsite"
+)
+
+type clock struct {
+	t time.Time
+}
+
+func (c *clock) now() time.Time {
+	return c.t
+}
+
+func newTestSampler(p Params, summaries *[]Summary) (*Sampler, *clock) {
+	var c = &clock{t: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	var s = NewSampler(p, func(x Summary) {
+		*summaries = append(*summaries, x)
+	})
+	s.now = c.now
+	return s, c
+}
+
+func count(s *Sampler, level ll.LogLevel, template string, n int) int {
+	var kept = 0
+	for i := 0; i < n; i++ {
+		if s.Allow(nil, level, template, 0) {
+			kept++
+		}
+	}
+	return kept
+}
+
+func TestFirstThenEveryMth(t *testing.T) {
+	var summaries []Summary
+	s, c := newTestSampler(Params{
+		Levels: map[ll.LogLevel]Rule{ll.DEBUG: {First: 3, Thereafter: 10}},
+		KeyBy:  KeyByMessage,
+	}, &summaries)
+
+	if n := count(s, ll.DEBUG, "a", 100); n != 3+9 {
+		t.Fatalf("expected 12 records to be kept, got %d", n)
+	}
+	if n := count(s, ll.DEBUG, "b", 3); n != 3 {
+		t.Fatalf("expected keys to be counted apart, got %d", n)
+	}
+	if n := count(s, ll.INFO, "a", 100); n != 100 {
+		t.Fatalf("expected levels without a rule to be kept, got %d", n)
+	}
+
+	c.t = c.t.Add(time.Second)
+	if n := count(s, ll.DEBUG, "a", 3); n != 3 {
+		t.Fatalf("expected the counter to start over, got %d", n)
+	}
+
+	s.Flush()
+	if len(summaries) != 1 || summaries[0].Suppressed != 88 || summaries[0].Level != ll.DEBUG ||
+		len(summaries[0].Keys) != 1 || summaries[0].Keys[0] != (KeyCount{Level: "DEBUG", Key: "a", Count: 88}) {
+		t.Fatalf("unexpected summary: %+v", summaries)
+	}
+
+	s.Flush()
+	if len(summaries) != 1 {
+		t.Fatalf("expected no summary when nothing was suppressed: %+v", summaries)
+	}
+}
+
+func TestTokenBucketPerLevel(t *testing.T) {
+	var summaries []Summary
+	s, c := newTestSampler(Params{
+		Levels: map[ll.LogLevel]Rule{ll.INFO: {Rate: 10, Burst: 5}},
+	}, &summaries)
+
+	if n := count(s, ll.INFO, "", 20); n != 5 {
+		t.Fatalf("expected the burst to be kept, got %d", n)
+	}
+	c.t = c.t.Add(300 * time.Millisecond)
+	if n := count(s, ll.INFO, "", 20); n != 3 {
+		t.Fatalf("expected three tokens after 300ms, got %d", n)
+	}
+	c.t = c.t.Add(time.Hour)
+	if n := count(s, ll.INFO, "", 20); n != 5 {
+		t.Fatalf("expected the bucket to be capped at the burst, got %d", n)
+	}
+}
+
+func TestKeyByCallSite(t *testing.T) {
+	var summaries []Summary
+	s, _ := newTestSampler(Params{
+		Levels: map[ll.LogLevel]Rule{ll.WARN: {First: 1}},
+	}, &summaries)
+
+	var kept = 0
+	for i := 0; i < 5; i++ {
+		callsite.Call(func() {
+			if s.Allow(nil, ll.WARN, "same message", 0) {
+				kept++
+			}
+		})
+		callsite.CallAgain(func() {
+			if s.Allow(nil, ll.WARN, "same message", 0) {
+				kept++
+			}
+		})
+	}
+	if kept != 2 {
+		t.Fatalf("expected one record per call site, got %d", kept)
+	}
+
+	s.Flush()
+	if len(summaries) != 1 || len(summaries[0].Keys) != 2 || summaries[0].Keys[0].Count != 4 {
+		t.Fatalf("unexpected summary: %+v", summaries)
+	}
+}
+
+func TestSummariesPerOrigin(t *testing.T) {
+	var summaries []Summary
+	s, _ := newTestSampler(Params{
+		Levels: map[ll.LogLevel]Rule{ll.INFO: {First: 1}},
+		KeyBy:  KeyByMessage,
+	}, &summaries)
+
+	// the counters are shared, the suppressed records are reported per origin
+	for _, origin := range []string{"parent", "child", "child", "parent", "child"} {
+		s.Allow(origin, ll.INFO, "same message", 0)
+	}
+
+	s.Flush()
+	if len(summaries) != 2 || summaries[0].Origin != "child" || summaries[0].Suppressed != 3 ||
+		summaries[1].Origin != "parent" || summaries[1].Suppressed != 1 {
+		t.Fatalf("expected one summary per origin: %+v", summaries)
+	}
+	if m := summaries[0].Meta(); m["suppressed"] != 3 || len(m["keys"].([]KeyCount)) != 1 {
+		t.Fatalf("unexpected summary meta: %#v", m)
+	}
+}
+
+func TestKeyByCallSiteSkipsFrames(t *testing.T) {
+	var summaries []Summary
+	s, _ := newTestSampler(Params{
+		Levels: map[ll.LogLevel]Rule{ll.WARN: {First: 1}},
+	}, &summaries)
+
+	for _, skip := range []int{0, 0, 1, 1} {
+		var skip = skip
+		callsite.Helper(func() {
+			s.Allow(nil, ll.WARN, "", skip)
+		})
+	}
+
+	s.Flush()
+	// the call in Helper is the call site with one frame skipped, the call in Call without
+	if len(summaries) != 1 || len(summaries[0].Keys) != 2 || summaries[0].Keys[0].Key == summaries[0].Keys[1].Key {
+		t.Fatalf("expected a key per skip: %+v", summaries)
+	}
+}
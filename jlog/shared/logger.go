@@ -0,0 +1,121 @@
+package shared
+
+import (
+	"context"
+	"io"
+
+	ll "github.com/oresoftware/json-logging/jlog/level"
+	"github.com/oresoftware/json-logging/jlog/writer"
+)
+
+// Logger is implemented by both lib.Logger and mult.MultiLogger,
+// so that libraries can accept "a jlog logger" without caring which flavor the app wired up.
+// It only has the logging methods, so that other implementations (eg a test double) keep working
+// as loggers get new features, the rest are in the small interfaces below, which callers type assert:
+//
+//	if f, ok := log.(shared.Flusher); ok {
+//		f.Flush(ctx)
+//	}
+type Logger interface {
+	Trace(args ...interface{})
+	Debug(args ...interface{})
+	Info(args ...interface{})
+	Warn(args ...interface{})
+	Error(args ...interface{})
+	Critical(args ...interface{})
+
+	TraceF(s string, args ...interface{})
+	DebugF(s string, args ...interface{})
+	InfoF(s string, args ...interface{})
+	WarnF(s string, args ...interface{})
+	ErrorF(s string, args ...interface{})
+	CriticalF(s string, args ...interface{})
+
+	Log(level ll.LogLevel, args ...interface{})
+	LogF(level ll.LogLevel, s string, args ...interface{})
+
+	TraceCtx(ctx context.Context, args ...interface{})
+	DebugCtx(ctx context.Context, args ...interface{})
+	InfoCtx(ctx context.Context, args ...interface{})
+	WarnCtx(ctx context.Context, args ...interface{})
+	ErrorCtx(ctx context.Context, args ...interface{})
+	CriticalCtx(ctx context.Context, args ...interface{})
+
+	IsLevelEnabled(level ll.LogLevel) bool
+}
+
+// ChildMaker makes child loggers, which add meta fields to the records of their parent.
+// The concrete Child/TagPair return the concrete type, these return the interface so they can be used generically.
+type ChildMaker interface {
+	ChildLogger(m *map[string]interface{}) Logger
+	TagPairLogger(k string, v interface{}) Logger
+}
+
+// Namer makes named child loggers, see SetNamedLevel.
+type Namer interface {
+	NamedLogger(name string) Logger
+}
+
+// Locker makes a logger which holds the global write lock until the returned func is called.
+type Locker interface {
+	LockedLogger() (Logger, func())
+}
+
+// Describer reports the levels and outputs of a logger, for admin endpoints and dumps.
+type Describer interface {
+	EffectiveLevel() ll.LogLevel
+	Describe() LoggerInfo
+}
+
+// Flusher waits for queued records to be written, see FlushAll.
+type Flusher interface {
+	Flush(ctx context.Context) error
+}
+
+// Recoverer logs panics at CRITICAL, see PanicParams.
+type Recoverer interface {
+	Recover()
+	Go(fn func())
+}
+
+// Printer writes without the record format.
+type Printer interface {
+	JSON(args ...interface{})
+	RawJSON(args ...interface{})
+	PlainStdout(args ...interface{})
+	PlainStderr(args ...interface{})
+	NewLine()
+	Spaces(num int32)
+	Tabs(num int32)
+}
+
+// PanicParams says what happens after Recover/Go have logged a panic,
+// by default the panic is swallowed.
+type PanicParams struct {
+	IsRePanic bool // panic again with the same value
+	ExitCode  int  // if not 0, exit the process with this code, this wins over IsRePanic
+}
+
+// LoggerInfo is a snapshot of a logger's levels and outputs, for admin endpoints and dumps.
+type LoggerInfo struct {
+	App        string       `json:"app"`
+	Name       string       `json:"name,omitempty"`
+	Level      string       `json:"level"`                 // the level in effect, see EffectiveLevel
+	NamedLevel string       `json:"named_level,omitempty"` // the level set for the name or a prefix, if any
+	Outputs    []OutputInfo `json:"outputs"`
+}
+
+type OutputInfo struct {
+	Output  string `json:"output"` // stdout, stderr, a file name or the writer's type
+	Level   string `json:"level"`  // the threshold of the output, in effect
+	IsJSON  bool   `json:"json"`
+	Dropped uint64 `json:"dropped,omitempty"` // records dropped by an async writer, see writer.AsyncWriter
+}
+
+func DescribeOutput(w io.Writer, level ll.LogLevel, isJSON bool) OutputInfo {
+	var info = OutputInfo{Output: writer.Describe(w), Level: level.String(), IsJSON: isJSON}
+	if aw, ok := w.(*writer.AsyncWriter); ok {
+		info.Dropped = aw.Dropped()
+	}
+	return info
+}
@@ -0,0 +1,194 @@
+package shared
+
+import (
+	au "github.com/oresoftware/json-logging/jlog/au"
+	hlpr "github.com/oresoftware/json-logging/jlog/helper"
+)
+
+type MF = map[string]interface{}
+
+type M = map[string]interface{}
+
+// brand the below struct with unique ref
+type metaFieldsMarker struct{}
+
+var mfMarker = &metaFieldsMarker{}
+
+type KV struct {
+	Key   string
+	Value interface{}
+	*metaFieldsMarker
+}
+
+type L = []KV
+
+type MetaFields struct {
+	*metaFieldsMarker
+	UniqueMarker string
+	m            *MF
+}
+
+func NewMetaFields(m *MF) *MetaFields {
+	if m == nil {
+		m = &MF{}
+	}
+	return &MetaFields{
+		metaFieldsMarker: mfMarker,
+		UniqueMarker:     "UniqueMarker(Brand)",
+		m:                m,
+	}
+}
+
+// Map returns the underlying map, so that both lib and mult can read/write the fields.
+func (x *MetaFields) Map() *MF {
+	if x == nil {
+		return nil
+	}
+	return x.m
+}
+
+func MetaPairs(
+	k1 string, v1 interface{},
+	args ...interface{}) *MetaFields {
+	return MP(k1, v1, args...)
+}
+
+func MP(
+	k1 string, v1 interface{},
+	args ...interface{}) *MetaFields {
+
+	m := make(map[string]interface{})
+	nargs := append([]interface{}{k1, v1}, args...) // prepend the first two arguments to new slice
+
+	currKey := ""
+
+	for i, a := range nargs {
+
+		if i%2 == 0 {
+			// operate on keys
+			v, ok := a.(string)
+			if ok {
+				currKey = v
+			} else {
+				panic("even arguments must be strings, odd arguments are interface{}")
+			}
+			if len(nargs) < i+2 {
+				panic("a key needs a respective value.")
+			}
+			continue
+		}
+
+		// operate on values
+		m[currKey] = a
+	}
+
+	return NewMetaFields(&m)
+
+}
+
+type logIdMarker struct{}
+
+var myLogIdMarker = &logIdMarker{}
+
+type LogId struct {
+	*logIdMarker
+	Val string
+}
+
+func (x *LogId) GetLogId(isHyperLink bool) string {
+	// fmt.Println("\\e]8;;http://example.com\aThis is the link\\e]8;;\\e\\")
+	// fmt.Println(fmt.Sprintf("\033]8;;%s\033\\%s\033]8;;\033\\", "https://linkedin.com", "Go to Linked"))
+
+	// fmt.Println(fmt.Sprintf("\033]8;;%s\033\\%s\033]8;;\033\\", "Go to x", "Go to XX"))
+
+	if false && isHyperLink {
+		// return au.Col.Blue("xyz1").Hyperlink(fmt.Sprintf("foobarbas", x.Val)).HyperlinkTarget()
+		// return au.Col.Hyperlink("foo", "https://foo.com").HyperlinkTarget()
+		return au.Col.Hyperlink("foo", "https://foo.com").String()
+		// return au.Col.Blue("(Goto -> LogId)").Hyperlink(fmt.Sprintf("http://vibeirl.com/dev/links?%s", x.Val)).HyperlinkTarget()
+	} else {
+		// return fmt.Sprintf("(log-id:'%s')", x.Val)
+		return x.Val
+	}
+}
+
+func (x *LogId) IsLogId() bool {
+	return true
+}
+
+func Id(v string) *LogId {
+	return &LogId{myLogIdMarker, v}
+}
+
+type errorIdMarker struct{}
+
+var eidMarker = &errorIdMarker{}
+
+// ErrorId goes into the meta map under "error_id" when passed to a logging call,
+// like LogId does for "log_id".
+type ErrorId struct {
+	Id            string
+	errorIdMarker *errorIdMarker
+}
+
+// Opts changes how a single logging call behaves, it is not logged itself.
+// If more than one is passed they are merged, see MergeOpts.
+type Opts struct {
+	IsPrintStackTrace bool // attach a stack trace, whatever the level
+	IsSkipStackTrace  bool // never attach a stack trace, this wins over IsPrintStackTrace
+	SkipFrames        int  // frames to drop from the top of the stack trace and the caller, eg for logging helpers
+	IsMustFlush       bool // flush the output after the record is written
+	errorIdMarker     *errorIdMarker
+}
+
+// MergeOpts adds b to a (which may be nil), the flags are or'ed and SkipFrames are added up,
+// so that helpers which log on behalf of their caller can add their own Opts.
+func MergeOpts(a *Opts, b Opts) *Opts {
+	if a == nil {
+		return &b
+	}
+	return &Opts{
+		IsPrintStackTrace: a.IsPrintStackTrace || b.IsPrintStackTrace,
+		IsSkipStackTrace:  a.IsSkipStackTrace || b.IsSkipStackTrace,
+		SkipFrames:        a.SkipFrames + b.SkipFrames,
+		IsMustFlush:       a.IsMustFlush || b.IsMustFlush,
+	}
+}
+
+// SkipFrames adds up the SkipFrames of the Opts in args, for the checks which run before the args are
+// processed, eg keying sampled records by call site.
+func SkipFrames(args []interface{}) int {
+	var n = 0
+	for _, x := range args {
+		if z, ok := x.(*Opts); ok && z != nil {
+			n += z.SkipFrames
+		} else if z, ok := x.(Opts); ok {
+			n += z.SkipFrames
+		}
+	}
+	return n
+}
+
+func ErrId(id string) *ErrorId {
+	return &ErrorId{
+		id, eidMarker,
+	}
+}
+
+// ErrOpts is the same as ErrId, it is kept for compatibility.
+func ErrOpts(id string) *ErrorId {
+	return &ErrorId{
+		id, eidMarker,
+	}
+}
+
+// StackTrace is appended to the args of records at/above the logger's stack trace level.
+// ErrorTrace is the older text form, Frames is what the loggers attach.
+type StackTrace struct {
+	ErrorTrace *[]string    `json:",omitempty"`
+	Frames     []hlpr.Frame `json:",omitempty"`
+}
+
+func (s StackTrace) StackFrames() []hlpr.Frame {
+	return s.Frames
+}
@@ -0,0 +1,130 @@
+package shared
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	ll "github.com/oresoftware/json-logging/jlog/level"
+)
+
+// NamedLevelsEnv is read on first use, eg jlog_levels=db=DEBUG,http=WARN
+const NamedLevelsEnv = "jlog_levels"
+
+// levels set by logger name (see Named in lib/mult), a name inherits the level of its closest
+// dotted prefix, so "db" applies to "db.pool" unless "db.pool" has a level of its own.
+var namedLevels = struct {
+	mtx    sync.RWMutex
+	levels map[string]ll.LogLevel
+}{
+	levels: map[string]ll.LogLevel{},
+}
+
+var loadEnvOnce sync.Once
+
+// the env var is read on first use rather than in init, so that it can use levels added with ll.Register
+func loadEnv() {
+	loadEnvOnce.Do(func() {
+		var v = os.Getenv(NamedLevelsEnv)
+		if v == "" {
+			return
+		}
+		levels, err := ParseNamedLevels(v)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "json-logging: could not parse the", NamedLevelsEnv, "env var:", err)
+		}
+		namedLevels.mtx.Lock()
+		defer namedLevels.mtx.Unlock()
+		for name, level := range levels {
+			namedLevels.levels[name] = level
+		}
+	})
+}
+
+// JoinName is the full name of a named child logger, eg db + pool => db.pool
+func JoinName(parent string, name string) string {
+	if parent == "" {
+		return name
+	}
+	if name == "" {
+		return parent
+	}
+	return parent + "." + name
+}
+
+// SetNamedLevel sets the level of the loggers with this name, and of the ones below it.
+func SetNamedLevel(name string, level ll.LogLevel) {
+	loadEnv()
+	namedLevels.mtx.Lock()
+	defer namedLevels.mtx.Unlock()
+	namedLevels.levels[strings.TrimSpace(name)] = level
+}
+
+// ClearNamedLevel removes the level of a name, the loggers go back to the level of a prefix, or their own.
+func ClearNamedLevel(name string) {
+	loadEnv()
+	namedLevels.mtx.Lock()
+	defer namedLevels.mtx.Unlock()
+	delete(namedLevels.levels, strings.TrimSpace(name))
+}
+
+// NamedLevel returns the level of the closest name or dotted prefix with one, eg db.pool.conn, db.pool, db.
+func NamedLevel(name string) (ll.LogLevel, bool) {
+	if name == "" {
+		return 0, false
+	}
+	loadEnv()
+	namedLevels.mtx.RLock()
+	defer namedLevels.mtx.RUnlock()
+	for {
+		if level, ok := namedLevels.levels[name]; ok {
+			return level, true
+		}
+		var i = strings.LastIndexByte(name, '.')
+		if i < 0 {
+			return 0, false
+		}
+		name = name[:i]
+	}
+}
+
+// NamedLevels returns a copy of the levels set by name.
+func NamedLevels() map[string]ll.LogLevel {
+	loadEnv()
+	namedLevels.mtx.RLock()
+	defer namedLevels.mtx.RUnlock()
+	var results = make(map[string]ll.LogLevel, len(namedLevels.levels))
+	for k, v := range namedLevels.levels {
+		results[k] = v
+	}
+	return results
+}
+
+// ParseNamedLevels parses name=LEVEL pairs separated by commas, the valid pairs are returned
+// even if others are not.
+func ParseNamedLevels(s string) (map[string]ll.LogLevel, error) {
+	var results = map[string]ll.LogLevel{}
+	var bad []string
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		parts := strings.SplitN(pair, "=", 2)
+		name := strings.TrimSpace(parts[0])
+		if len(parts) < 2 || name == "" {
+			bad = append(bad, pair)
+			continue
+		}
+		level, ok := ll.Parse(parts[1])
+		if !ok {
+			bad = append(bad, pair)
+			continue
+		}
+		results[name] = level
+	}
+	if len(bad) > 0 {
+		return results, fmt.Errorf("invalid name=LEVEL pairs: %s", strings.Join(bad, ", "))
+	}
+	return results, nil
+}
@@ -0,0 +1,103 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/oresoftware/json-logging/jlog/writer"
+)
+
+// loggers which own an output are registered here (see RegisterOwner), so that they can be flushed on shutdown.
+// Child loggers are not registered, they share their outputs with the parent.
+var registry = struct {
+	mtx     sync.RWMutex
+	loggers []Logger
+}{}
+
+func RegisterLogger(l Logger) {
+	if l == nil {
+		return
+	}
+	registry.mtx.Lock()
+	defer registry.mtx.Unlock()
+	for _, z := range registry.loggers {
+		if z == l {
+			return
+		}
+	}
+	registry.loggers = append(registry.loggers, l)
+}
+
+// RegisterOwner registers l if one of its outputs is something other than stdout/stderr. A logger which
+// only writes to those has nothing of its own to flush or close, so it is left out and can be garbage collected.
+func RegisterOwner(l Logger, outputs ...io.Writer) {
+	for _, w := range outputs {
+		if w != nil && !writer.IsStdio(w) {
+			RegisterLogger(l)
+			return
+		}
+	}
+}
+
+func UnregisterLogger(l Logger) {
+	registry.mtx.Lock()
+	defer registry.mtx.Unlock()
+	for i, z := range registry.loggers {
+		if z == l {
+			registry.loggers = append(registry.loggers[:i], registry.loggers[i+1:]...)
+			return
+		}
+	}
+}
+
+func RegisteredLoggers() []Logger {
+	registry.mtx.RLock()
+	defer registry.mtx.RUnlock()
+	return append([]Logger(nil), registry.loggers...)
+}
+
+// FlushAll flushes every registered logger, stopping early if the context is done.
+func FlushAll(ctx context.Context) error {
+	var errs []error
+	for _, l := range RegisteredLoggers() {
+		f, ok := l.(Flusher)
+		if !ok {
+			continue
+		}
+		if err := f.Flush(ctx); err != nil {
+			errs = append(errs, err)
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Shutdown flushes every registered logger within the timeout, and then closes them (see Flusher and io.Closer).
+func Shutdown(timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var errs []error
+	if err := FlushAll(ctx); err != nil {
+		errs = append(errs, err)
+	}
+
+	if ctx.Err() == nil {
+		for _, l := range RegisteredLoggers() {
+			c, ok := l.(io.Closer)
+			if !ok {
+				continue
+			}
+			if err := c.Close(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
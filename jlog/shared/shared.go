@@ -16,6 +16,7 @@ var M1 = sync.RWMutex{}
 var IsTerminal = terminal.IsTerminal(int(os.Stdout.Fd()))
 var PID = os.Getpid()
 
+// Level and LevelToString only have the builtin levels, ll.Parse and ll.LogLevel.String know the registered ones too.
 var Level = map[string]ll.LogLevel{
 	"TRACE":    ll.TRACE,
 	"DEBUG":    ll.DEBUG,
@@ -42,6 +43,9 @@ func ToLogLevel(s string) ll.LogLevel {
 	if v, ok := Level[cleanVal]; ok {
 		return v
 	}
+	if v, ok := ll.Parse(cleanVal); ok {
+		return v
+	}
 	fmt.Println(fmt.Sprintf("warning no log level could be retrieved via value: '%s'", s))
 	return ll.TRACE
 }
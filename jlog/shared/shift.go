@@ -0,0 +1,96 @@
+package shared
+
+import (
+	"math"
+	"sort"
+	"sync/atomic"
+
+	ll "github.com/oresoftware/json-logging/jlog/level"
+)
+
+// the number of registered levels every logger is moved by, negative is more verbose, see ll.Shift
+var levelShift atomic.Int32
+
+// the least and most severe levels the loggers were checked against before the shift (see Shifted),
+// ShiftLevels stops where neither of them can move any further
+var minSeverity, maxSeverity atomic.Int64
+
+func init() {
+	resetSeverities()
+}
+
+func resetSeverities() {
+	minSeverity.Store(math.MaxInt64)
+	maxSeverity.Store(math.MinInt64)
+}
+
+// Shifted moves a logger's level by the total shift, see ShiftLevels. Loggers call it with the level
+// they were set to every time they check a record's level, so that ShiftLevels knows which levels are in use.
+func Shifted(level ll.LogLevel) ll.LogLevel {
+	var s = int64(level.Severity())
+	for {
+		var old = minSeverity.Load()
+		if s >= old || minSeverity.CompareAndSwap(old, s) {
+			break
+		}
+	}
+	for {
+		var old = maxSeverity.Load()
+		if s <= old || maxSeverity.CompareAndSwap(old, s) {
+			break
+		}
+	}
+	return ll.Shift(level, LevelShift())
+}
+
+// ShiftLevels makes every logger n levels more (negative) or less (positive) verbose, on top of the
+// levels they were set to, and returns the total shift. Loggers check it on every record, so nothing
+// about the loggers themselves changes, and child loggers follow.
+//
+// The total does not wrap around, it is clamped so that each step changes the level of at least one logger:
+// going more verbose stops once every level in use has become the least severe registered level (eg TRACE),
+// and going less verbose once every one has become the most severe (eg CRITICAL). So extra steps in one
+// direction never have to be undone in the other. The levels in use are the ones loggers were checked against
+// since the start (or ResetLevelShift), with none yet the total is clamped to the number of registered levels - 1.
+func ShiftLevels(n int) int {
+	var levels = ll.Levels()
+	var lo, hi = -(len(levels) - 1), len(levels) - 1
+
+	// the index Shift starts from, -1 below the least severe level
+	index := func(severity int64) int {
+		return sort.Search(len(levels), func(i int) bool {
+			return int64(levels[i].Severity) > severity
+		}) - 1
+	}
+	if least, most := minSeverity.Load(), maxSeverity.Load(); least <= most {
+		lo, hi = -index(most), len(levels)-1-index(least)
+		if lo > 0 {
+			lo = 0
+		}
+	}
+
+	for {
+		var old = levelShift.Load()
+		var v = old + int32(n)
+		if v > int32(hi) {
+			v = int32(hi)
+		}
+		if v < int32(lo) {
+			v = int32(lo)
+		}
+		if levelShift.CompareAndSwap(old, v) {
+			return int(v)
+		}
+	}
+}
+
+// LevelShift returns the total shift.
+func LevelShift() int {
+	return int(levelShift.Load())
+}
+
+// ResetLevelShift puts every logger back to the level it was set to, and forgets the levels in use.
+func ResetLevelShift() {
+	levelShift.Store(0)
+	resetSeverities()
+}
@@ -0,0 +1,134 @@
+package signals
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/oresoftware/json-logging/jlog/bunion"
+	ll "github.com/oresoftware/json-logging/jlog/level"
+	"github.com/oresoftware/json-logging/jlog/shared"
+)
+
+type Params struct {
+	More   os.Signal // makes every logger one level more verbose, SIGUSR1 if nil
+	Less   os.Signal // makes every logger one level less verbose, SIGUSR2 if nil
+	Dump   os.Signal // writes the state of the loggers as a record, eg syscall.SIGHUP, off if nil
+	Output io.Writer // where the dump and the level changes are written, os.Stderr if nil
+}
+
+// Notify starts handling the signals, until stop is called. The level changes go through
+// shared.ShiftLevels, so the loggers see them on their next record, without their Mtx being taken.
+func Notify(p Params) (stop func()) {
+	if p.More == nil {
+		p.More = syscall.SIGUSR1
+	}
+	if p.Less == nil {
+		p.Less = syscall.SIGUSR2
+	}
+	if p.Output == nil {
+		p.Output = os.Stderr
+	}
+
+	var sigs []os.Signal
+	for _, s := range []os.Signal{p.More, p.Less, p.Dump} {
+		if s != nil {
+			sigs = append(sigs, s)
+		}
+	}
+
+	var c = make(chan os.Signal, 1)
+	var done = make(chan struct{})
+	signal.Notify(c, sigs...)
+
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case s := <-c:
+				handle(p, s)
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(c)
+			close(done)
+		})
+	}
+}
+
+func handle(p Params, s os.Signal) {
+	var err error
+	switch s {
+	case p.More:
+		err = write(p.Output, "json-logging: levels shifted", shared.MF{"level_shift": shared.ShiftLevels(-1)})
+	case p.Less:
+		err = write(p.Output, "json-logging: levels shifted", shared.MF{"level_shift": shared.ShiftLevels(1)})
+	case p.Dump:
+		err = Dump(p.Output)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "json-logging: could not handle signal", s, err)
+	}
+}
+
+// Dump writes the registered loggers, their levels and outputs (with drop counters), the levels set
+// by name and the level shift, as a single @bunion:v1 record. The loggers are read with their Mtx held.
+func Dump(w io.Writer) error {
+	var loggers = []shared.LoggerInfo{}
+	for _, l := range shared.RegisteredLoggers() {
+		if d, ok := l.(shared.Describer); ok {
+			loggers = append(loggers, d.Describe())
+		}
+	}
+
+	var named = map[string]string{}
+	for name, level := range shared.NamedLevels() {
+		named[name] = level.String()
+	}
+
+	var levels = []string{}
+	for _, info := range ll.Levels() {
+		levels = append(levels, info.Name)
+	}
+
+	return write(w, "json-logging: logger state", shared.MF{
+		"loggers":      loggers,
+		"named_levels": named,
+		"levels":       levels,
+		"level_shift":  shared.LevelShift(),
+	})
+}
+
+// write bypasses the loggers, so that the record is written whatever their levels are
+func write(w io.Writer, message string, meta shared.MF) error {
+	hostName, _ := os.Hostname()
+	meta["log_num"] = shared.GetNextLogNum()
+
+	b, err := json.Marshal(&bunion.Record{
+		AppName:  "json-logging",
+		Level:    ll.WARN.JSONName(),
+		PID:      shared.PID,
+		HostName: hostName,
+		Date:     time.Now(),
+		Meta:     meta,
+		Args:     []interface{}{message},
+	})
+	if err != nil {
+		return err
+	}
+
+	shared.M1.RLock()
+	defer shared.M1.RUnlock()
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
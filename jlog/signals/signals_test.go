@@ -0,0 +1,139 @@
+package signals
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/oresoftware/json-logging/jlog/bunion"
+	ll "github.com/oresoftware/json-logging/jlog/level"
+	"github.com/oresoftware/json-logging/jlog/lib"
+	"github.com/oresoftware/json-logging/jlog/mult"
+	"github.com/oresoftware/json-logging/jlog/shared"
+	"github.com/oresoftware/json-logging/jlog/writer"
+)
+
+type syncBuffer struct {
+	mtx sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return b.buf.String()
+}
+
+func waitFor(t *testing.T, what string, f func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		if f() {
+			return
+		}
+	}
+	t.Fatalf("timed out waiting for %s", what)
+}
+
+func TestSignalsShiftLevelsAndDump(t *testing.T) {
+	defer shared.ResetLevelShift()
+
+	var out syncBuffer
+	log := lib.CreateLogger("signals-lib").SetOutput(io.Discard).SetLogLevel(ll.INFO)
+	child := log.TagPair("request_id", "abc")
+	multi := mult.New("signals-mult", "", []*mult.FileLevel{{Level: ll.WARN, Writer: io.Discard}})
+	multi.SetAsync(writer.AsyncParams{})
+	defer log.Close()
+	defer multi.Close()
+
+	var stop = Notify(Params{Dump: syscall.SIGHUP, Output: &out})
+	defer stop()
+
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGUSR1); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the level shift", func() bool { return shared.LevelShift() == -1 })
+	if !log.IsDebugEnabled() || !child.IsDebugEnabled() || !multi.IsInfoEnabled() || multi.IsDebugEnabled() {
+		t.Fatal("expected every logger to be one level more verbose")
+	}
+
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the dump", func() bool { return strings.Contains(out.String(), "logger state") })
+
+	var dump *bunion.Record
+	var d = bunion.NewDecoder(strings.NewReader(out.String()))
+	for {
+		rec, err := d.Decode()
+		if err != nil {
+			break
+		}
+		if rec.Args[0] == "json-logging: logger state" {
+			dump = rec
+		}
+	}
+	if dump == nil || dump.Meta["level_shift"] != float64(-1) {
+		t.Fatalf("unexpected dump: %#v", dump)
+	}
+	var apps = map[string]map[string]interface{}{}
+	for _, v := range dump.Meta["loggers"].([]interface{}) {
+		var info = v.(map[string]interface{})
+		apps[info["app"].(string)] = info
+	}
+	if apps["signals-lib"]["level"] != "DEBUG" || apps["signals-mult"]["level"] != "INFO" {
+		t.Fatalf("unexpected levels in the dump: %#v", apps)
+	}
+	var output = apps["signals-mult"]["outputs"].([]interface{})[0].(map[string]interface{})
+	if !strings.HasPrefix(output["output"].(string), "async:") {
+		t.Fatalf("unexpected output in the dump: %#v", output)
+	}
+
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGUSR2); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the level shift", func() bool { return shared.LevelShift() == 0 })
+	if log.IsDebugEnabled() || !log.IsInfoEnabled() {
+		t.Fatal("expected the levels to be back")
+	}
+}
+
+func TestShiftLevelsStopsWhereNoLevelCanMove(t *testing.T) {
+	shared.ResetLevelShift()
+	defer shared.ResetLevelShift()
+
+	log := lib.CreateLogger("shift-lib").SetOutput(io.Discard).SetLogLevel(ll.INFO)
+	multi := mult.New("shift-mult", "", []*mult.FileLevel{{Level: ll.WARN, Writer: io.Discard}})
+	log.IsInfoEnabled()
+	multi.IsInfoEnabled()
+
+	// WARN is the most severe level in use, it is TRACE after three steps
+	if v := shared.ShiftLevels(-10); v != -3 || multi.EffectiveLevel() != ll.TRACE {
+		t.Fatalf("unexpected shift: %d %v", v, multi.EffectiveLevel())
+	}
+	if v := shared.ShiftLevels(1); v != -2 || multi.EffectiveLevel() != ll.DEBUG || log.EffectiveLevel() != ll.TRACE {
+		t.Fatalf("expected one step back to change the levels: %d %v %v", v, multi.EffectiveLevel(), log.EffectiveLevel())
+	}
+
+	// INFO is the least severe level in use, it is CRITICAL after three steps
+	if v := shared.ShiftLevels(10); v != 3 || log.EffectiveLevel() != ll.CRITICAL {
+		t.Fatalf("unexpected shift: %d %v", v, log.EffectiveLevel())
+	}
+	if v := shared.ShiftLevels(-1); v != 2 || log.EffectiveLevel() != ll.ERROR {
+		t.Fatalf("expected one step back to change the levels: %d %v", v, log.EffectiveLevel())
+	}
+
+	shared.ResetLevelShift()
+	if v := shared.ShiftLevels(-10); v != -(len(ll.Levels()) - 1) {
+		t.Fatalf("expected the number of levels - 1 with no levels in use: %d", v)
+	}
+}
@@ -0,0 +1,281 @@
+package tail
+
+import (
+	"bytes"
+	"container/heap"
+	"context"
+	"io"
+	"os"
+	"time"
+
+	"github.com/oresoftware/json-logging/jlog/bunion"
+)
+
+type FollowParams struct {
+	Paths        []string
+	FromStart    bool          // read the files from the beginning, instead of only new lines
+	PollInterval time.Duration // defaults to 250ms
+	Window       time.Duration // how long records are held back to be merged in order, defaults to 500ms
+}
+
+// Entry is a line read from one of the files, Record is nil if the line is not a @bunion record.
+type Entry struct {
+	Source int // index into FollowParams.Paths
+	Path   string
+	Record *bunion.Record
+	Line   []byte
+}
+
+// Follow tails the files, like tail -F, and merges the records by their timestamp.
+// A record can only be reordered with records that arrived within the window.
+// Truncated files are read again from the start, and rotated (renamed/recreated) files are reopened,
+// once the rest of the old file has been read, including a last line without a newline.
+// The channel is closed once ctx is done.
+func Follow(ctx context.Context, p FollowParams) (<-chan Entry, error) {
+	if p.PollInterval <= 0 {
+		p.PollInterval = 250 * time.Millisecond
+	}
+	if p.Window <= 0 {
+		p.Window = 500 * time.Millisecond
+	}
+
+	var tailers = make([]*tailer, len(p.Paths))
+	for i, path := range p.Paths {
+		t, err := newTailer(i, path, p.FromStart)
+		if err != nil {
+			for _, t := range tailers[:i] {
+				t.close()
+			}
+			return nil, err
+		}
+		tailers[i] = t
+	}
+
+	var in = make(chan pending, 1024)
+	var out = make(chan Entry, 1024)
+
+	for _, t := range tailers {
+		go t.run(ctx, p.PollInterval, in)
+	}
+	go merge(ctx, p.Window, in, out)
+
+	return out, nil
+}
+
+type pending struct {
+	Entry
+	ts      time.Time // the record date, or the date of the previous record from the same source
+	arrived time.Time
+	seq     uint64
+}
+
+type pendingHeap []pending
+
+func (h pendingHeap) Len() int { return len(h) }
+
+func (h pendingHeap) Less(i, j int) bool {
+	if !h[i].ts.Equal(h[j].ts) {
+		return h[i].ts.Before(h[j].ts)
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h pendingHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *pendingHeap) Push(x interface{}) { *h = append(*h, x.(pending)) }
+
+func (h *pendingHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+func merge(ctx context.Context, window time.Duration, in <-chan pending, out chan<- Entry) {
+	defer close(out)
+
+	var h pendingHeap
+	var seq uint64
+	var ticker = time.NewTicker(window / 4)
+	defer ticker.Stop()
+
+	// arrival order, to find the entry that has waited the longest
+	type arrival struct {
+		seq uint64
+		at  time.Time
+	}
+	var arrivals []arrival
+	var released = map[uint64]bool{}
+
+	var release = func(now time.Time) bool {
+		for len(arrivals) > 0 {
+			if released[arrivals[0].seq] {
+				delete(released, arrivals[0].seq)
+				arrivals = arrivals[1:]
+				continue
+			}
+			if now.Sub(arrivals[0].at) < window {
+				return true
+			}
+			// something has waited long enough, the oldest timestamp goes first,
+			// so that a record is never held back for much longer than the window
+			var x = heap.Pop(&h).(pending)
+			released[x.seq] = true
+			select {
+			case out <- x.Entry:
+			case <-ctx.Done():
+				return false
+			}
+		}
+		return true
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case x := <-in:
+			seq++
+			x.seq = seq
+			heap.Push(&h, x)
+			arrivals = append(arrivals, arrival{x.seq, x.arrived})
+		case now := <-ticker.C:
+			if !release(now) {
+				return
+			}
+		}
+	}
+}
+
+type tailer struct {
+	source  int
+	path    string
+	file    *os.File
+	offset  int64
+	partial []byte
+	lastTs  time.Time
+}
+
+func newTailer(source int, path string, fromStart bool) (*tailer, error) {
+	var t = &tailer{source: source, path: path}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	t.file = f
+	if !fromStart {
+		if t.offset, err = f.Seek(0, io.SeekEnd); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+func (t *tailer) close() {
+	if t.file != nil {
+		t.file.Close()
+		t.file = nil
+	}
+}
+
+func (t *tailer) run(ctx context.Context, interval time.Duration, in chan<- pending) {
+	defer t.close()
+
+	var buf = make([]byte, 64*1024)
+
+	for {
+		if !t.readAll(ctx, buf, in) {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+
+		if !t.checkRotation(ctx, buf, in) {
+			return
+		}
+	}
+}
+
+// readAll reads everything that is there, it is false once ctx is done
+func (t *tailer) readAll(ctx context.Context, buf []byte, in chan<- pending) bool {
+	for {
+		n, err := t.file.Read(buf)
+		if n > 0 {
+			t.offset += int64(n)
+			if !t.emit(ctx, buf[:n], in) {
+				return false
+			}
+		}
+		if err != nil || n == 0 {
+			return true
+		}
+	}
+}
+
+// checkRotation reopens the path if the file was replaced, and rewinds if it was truncated,
+// it is false once ctx is done
+func (t *tailer) checkRotation(ctx context.Context, buf []byte, in chan<- pending) bool {
+	cur, err := t.file.Stat()
+	if err != nil {
+		return true
+	}
+
+	if info, err := os.Stat(t.path); err == nil && !os.SameFile(cur, info) {
+		f, err := os.Open(t.path)
+		if err != nil {
+			return true
+		}
+		// what was written to the old file since the last read, eg just before the rename,
+		// and its last line, even if it is not terminated
+		if !t.readAll(ctx, buf, in) || (len(t.partial) > 0 && !t.emit(ctx, []byte{'\n'}, in)) {
+			f.Close()
+			return false
+		}
+		t.file.Close()
+		t.file, t.offset, t.partial = f, 0, nil
+		return true
+	}
+
+	if cur.Size() < t.offset {
+		if _, err := t.file.Seek(0, io.SeekStart); err == nil {
+			t.offset, t.partial = 0, nil
+		}
+	}
+	return true
+}
+
+// emit sends the complete lines in b, and keeps the rest for the next read
+func (t *tailer) emit(ctx context.Context, b []byte, in chan<- pending) bool {
+	t.partial = append(t.partial, b...)
+
+	for {
+		i := bytes.IndexByte(t.partial, '\n')
+		if i < 0 {
+			return true
+		}
+		var line = bytes.TrimRight(t.partial[:i], "\r")
+		t.partial = t.partial[i+1:]
+
+		var e = Entry{Source: t.source, Path: t.path, Line: append([]byte(nil), line...)}
+		if rec, err := bunion.Parse(e.Line); err == nil {
+			e.Record = rec
+			if !rec.Date.IsZero() {
+				t.lastTs = rec.Date
+			}
+		}
+
+		// lines without a date sort right after the previous record from the same file
+		var x = pending{Entry: e, ts: t.lastTs, arrived: time.Now()}
+
+		select {
+		case in <- x:
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
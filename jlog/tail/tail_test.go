@@ -0,0 +1,168 @@
+package tail
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func record(app, date, msg string) string {
+	return fmt.Sprintf(`["@bunion:v1",%q,"INFO",1,"h",%q,{},[%q]]`+"\n", app, date, msg)
+}
+
+func appendTo(t *testing.T, path, s string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(s); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func next(t *testing.T, ch <-chan Entry) Entry {
+	t.Helper()
+	select {
+	case e := <-ch:
+		return e
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for an entry")
+	}
+	return Entry{}
+}
+
+func TestFollowMergesByTimestamp(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.log"), filepath.Join(dir, "b.log")
+	appendTo(t, a, record("a", "2024-01-02 03:04:00.000000", "old"))
+	appendTo(t, b, "")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch, err := Follow(ctx, FollowParams{Paths: []string{a, b}, PollInterval: 10 * time.Millisecond, Window: 200 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the existing line is skipped, b's records are older than a's
+	appendTo(t, a, record("a", "2024-01-02 03:04:05.000003", "a1"))
+	appendTo(t, b, record("b", "2024-01-02 03:04:05.000001", "b1"))
+	appendTo(t, a, "plain text\n")
+	appendTo(t, b, record("b", "2024-01-02 03:04:05.000002", "b2"))
+
+	var got []string
+	for i := 0; i < 4; i++ {
+		e := next(t, ch)
+		if e.Record == nil {
+			got = append(got, string(e.Line))
+		} else {
+			got = append(got, e.Record.Args[0].(string))
+		}
+	}
+
+	want := "[b1 b2 a1 plain text]"
+	if fmt.Sprint(got) != want {
+		t.Fatalf("expected %s, got %v", want, got)
+	}
+}
+
+func TestFollowTruncationAndRotation(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.log")
+	appendTo(t, a, record("a", "2024-01-02 03:04:00.000000", "first"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch, err := Follow(ctx, FollowParams{Paths: []string{a}, FromStart: true, PollInterval: 10 * time.Millisecond, Window: 20 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if e := next(t, ch); e.Record == nil || e.Record.Args[0] != "first" || e.Source != 0 || e.Path != a {
+		t.Fatalf("unexpected entry: %+v", e)
+	}
+
+	// truncated, then rewritten
+	if err := os.Truncate(a, 0); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	appendTo(t, a, record("a", "2024-01-02 03:04:01.000000", "after truncate"))
+	if e := next(t, ch); e.Record == nil || e.Record.Args[0] != "after truncate" {
+		t.Fatalf("unexpected entry: %+v", e)
+	}
+
+	// moved away and recreated, like a rotating file does
+	if err := os.Rename(a, a+".1"); err != nil {
+		t.Fatal(err)
+	}
+	appendTo(t, a, record("a", "2024-01-02 03:04:02.000000", "after rotate"))
+	if e := next(t, ch); e.Record == nil || e.Record.Args[0] != "after rotate" {
+		t.Fatalf("unexpected entry: %+v", e)
+	}
+
+	cancel()
+	for range ch {
+	}
+}
+
+func TestFollowDrainsTheOldFileOnRotation(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.log")
+	appendTo(t, a, "")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// a long poll interval, so the rotation below happens between two reads
+	ch, err := Follow(ctx, FollowParams{Paths: []string{a}, PollInterval: 200 * time.Millisecond, Window: 20 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	// a writer which still has the old file open appends to it just before and after the rename
+	w, err := os.OpenFile(a, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	if _, err := w.WriteString(record("a", "2024-01-02 03:04:00.000000", "before rename")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(a, a+".1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.WriteString(record("a", "2024-01-02 03:04:01.000000", "after rename") + "unterminated"); err != nil {
+		t.Fatal(err)
+	}
+	appendTo(t, a, record("a", "2024-01-02 03:04:02.000000", "new file"))
+
+	var got []string
+	for i := 0; i < 4; i++ {
+		e := next(t, ch)
+		if e.Record == nil {
+			got = append(got, string(e.Line))
+		} else {
+			got = append(got, e.Record.Args[0].(string))
+		}
+	}
+
+	want := "[before rename after rename unterminated new file]"
+	if fmt.Sprint(got) != want {
+		t.Fatalf("expected %s, got %v", want, got)
+	}
+}
+
+func TestFollowMissingFile(t *testing.T) {
+	if _, err := Follow(context.Background(), FollowParams{Paths: []string{filepath.Join(t.TempDir(), "nope.log")}}); err == nil {
+		t.Fatal("expected an error")
+	}
+}
@@ -0,0 +1,280 @@
+package writer
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	ll "github.com/oresoftware/json-logging/jlog/level"
+)
+
+type DropPolicy int
+
+const (
+	Block          DropPolicy = iota // wait for room in the queue
+	DropNewest     DropPolicy = iota // drop the record being written
+	DropOldest     DropPolicy = iota // drop the oldest queued record to make room
+	DropBelowLevel DropPolicy = iota // drop records below AsyncParams.MinLevel, block for the rest
+)
+
+var ErrAsyncClosed = errors.New("json-logging: async writer is closed")
+
+// the async writers which are not closed, see FlushAll
+var openAsync sync.Map
+
+// LevelWriter is implemented by writers that care about the level of a record, eg AsyncWriter.
+type LevelWriter interface {
+	WriteLevel(level ll.LogLevel, p []byte) (int, error)
+}
+
+type AsyncParams struct {
+	Writer        io.Writer // the destination, defaults to stdout
+	Capacity      int       // max number of queued records, defaults to 1024
+	BatchSize     int       // max records per write(2), defaults to 128
+	FlushInterval time.Duration
+	Policy        DropPolicy
+	MinLevel      ll.LogLevel // for DropBelowLevel
+}
+
+type AsyncStats struct {
+	Queued  uint64
+	Written uint64
+	Dropped uint64
+	Errors  uint64
+}
+
+type asyncRecord struct {
+	level    ll.LogLevel
+	hasLevel bool
+	b        []byte
+}
+
+// AsyncWriter queues serialized records in a bounded ring buffer,
+// a background goroutine writes them to the destination in batches.
+type AsyncWriter struct {
+	p        AsyncParams
+	mtx      sync.Mutex
+	notEmpty *sync.Cond
+	notFull  *sync.Cond
+	drained  *sync.Cond
+	ring     []asyncRecord
+	head     int
+	count    int
+	inFlight bool
+	closed   bool
+	done     chan struct{}
+
+	queued  uint64
+	written uint64
+	dropped uint64
+	errors  uint64
+}
+
+func NewAsyncWriter(p AsyncParams) *AsyncWriter {
+	if p.Writer == nil {
+		p.Writer = os.Stdout
+	}
+	if p.Capacity < 1 {
+		p.Capacity = 1024
+	}
+	if p.BatchSize < 1 {
+		p.BatchSize = 128
+	}
+
+	var w = &AsyncWriter{
+		p:    p,
+		ring: make([]asyncRecord, p.Capacity),
+		done: make(chan struct{}),
+	}
+	w.notEmpty = sync.NewCond(&w.mtx)
+	w.notFull = sync.NewCond(&w.mtx)
+	w.drained = sync.NewCond(&w.mtx)
+
+	openAsync.Store(w, true)
+	go w.run()
+	return w
+}
+
+// Destination is the writer that AsyncWriter writes to.
+func (w *AsyncWriter) Destination() io.Writer {
+	return w.p.Writer
+}
+
+func (w *AsyncWriter) Write(p []byte) (int, error) {
+	return w.enqueue(asyncRecord{b: p})
+}
+
+func (w *AsyncWriter) WriteLevel(level ll.LogLevel, p []byte) (int, error) {
+	return w.enqueue(asyncRecord{level: level, hasLevel: true, b: p})
+}
+
+func (w *AsyncWriter) enqueue(r asyncRecord) (int, error) {
+	var n = len(r.b)
+	// the caller may reuse p after we return
+	r.b = append([]byte(nil), r.b...)
+
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	for w.count == len(w.ring) && !w.closed {
+		switch w.p.Policy {
+		case DropNewest:
+			atomic.AddUint64(&w.dropped, 1)
+			return n, nil
+		case DropOldest:
+			w.ring[w.head] = asyncRecord{}
+			w.head = (w.head + 1) % len(w.ring)
+			w.count--
+			atomic.AddUint64(&w.dropped, 1)
+		case DropBelowLevel:
+			if r.hasLevel && !r.level.AtLeast(w.p.MinLevel) {
+				atomic.AddUint64(&w.dropped, 1)
+				return n, nil
+			}
+			w.notFull.Wait()
+		default:
+			w.notFull.Wait()
+		}
+	}
+
+	if w.closed {
+		return 0, ErrAsyncClosed
+	}
+
+	w.ring[(w.head+w.count)%len(w.ring)] = r
+	w.count++
+	atomic.AddUint64(&w.queued, 1)
+	w.notEmpty.Signal()
+	return n, nil
+}
+
+func (w *AsyncWriter) run() {
+	defer close(w.done)
+
+	var buf []byte
+
+	for {
+		w.mtx.Lock()
+		for w.count == 0 && !w.closed {
+			w.notEmpty.Wait()
+		}
+		if w.count == 0 && w.closed {
+			w.mtx.Unlock()
+			return
+		}
+
+		buf = buf[:0]
+		var n = 0
+		for w.count > 0 && n < w.p.BatchSize {
+			buf = append(buf, w.ring[w.head].b...)
+			w.ring[w.head] = asyncRecord{}
+			w.head = (w.head + 1) % len(w.ring)
+			w.count--
+			n++
+		}
+		w.inFlight = true
+		w.notFull.Broadcast()
+		w.mtx.Unlock()
+
+		if _, err := Write(w.p.Writer, buf); err != nil {
+			atomic.AddUint64(&w.errors, 1)
+		} else {
+			atomic.AddUint64(&w.written, uint64(n))
+		}
+
+		w.mtx.Lock()
+		w.inFlight = false
+		if w.count == 0 {
+			w.drained.Broadcast()
+		}
+		w.mtx.Unlock()
+
+		if w.p.FlushInterval > 0 {
+			// let a batch build up
+			time.Sleep(w.p.FlushInterval)
+		}
+	}
+}
+
+// Flush waits until every queued record has been written, or the context is done.
+func (w *AsyncWriter) Flush(ctx context.Context) error {
+	// wakes up the wait below when the context is done, so nothing is left blocked
+	stop := context.AfterFunc(ctx, func() {
+		w.mtx.Lock()
+		w.drained.Broadcast()
+		w.mtx.Unlock()
+	})
+	defer stop()
+
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	for w.count > 0 || w.inFlight {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		w.drained.Wait()
+	}
+	return nil
+}
+
+// FlushAll flushes every async writer which is not closed. NewLoggerWithLock uses it after taking
+// the global lock, so no queued record gets written while the locked logger writes.
+func FlushAll(ctx context.Context) error {
+	var errs []error
+	openAsync.Range(func(k, _ any) bool {
+		if err := k.(*AsyncWriter).Flush(ctx); err != nil {
+			errs = append(errs, err)
+		}
+		return true
+	})
+	return errors.Join(errs...)
+}
+
+// Sync flushes the queue and then syncs the destination.
+func (w *AsyncWriter) Sync() error {
+	if err := w.Flush(context.Background()); err != nil {
+		return err
+	}
+	return Sync(w.p.Writer)
+}
+
+// Close drains the queue and stops the background goroutine, the destination is not closed.
+func (w *AsyncWriter) Close() error {
+	w.mtx.Lock()
+	if w.closed {
+		w.mtx.Unlock()
+		return nil
+	}
+	w.closed = true
+	openAsync.Delete(w)
+	w.notEmpty.Broadcast()
+	w.notFull.Broadcast()
+	w.mtx.Unlock()
+	<-w.done
+	return nil
+}
+
+func (w *AsyncWriter) Dropped() uint64 {
+	return atomic.LoadUint64(&w.dropped)
+}
+
+func (w *AsyncWriter) Stats() AsyncStats {
+	return AsyncStats{
+		Queued:  atomic.LoadUint64(&w.queued),
+		Written: atomic.LoadUint64(&w.written),
+		Dropped: atomic.LoadUint64(&w.dropped),
+		Errors:  atomic.LoadUint64(&w.errors),
+	}
+}
+
+// WriteLevel writes a record via LevelWriter if w supports it, otherwise via Write.
+func WriteLevel(w io.Writer, level ll.LogLevel, p []byte) (int, error) {
+	if lw, ok := w.(LevelWriter); ok {
+		return lw.WriteLevel(level, p)
+	}
+	return Write(w, p)
+}
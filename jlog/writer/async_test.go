@@ -0,0 +1,155 @@
+package writer
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	ll "github.com/oresoftware/json-logging/jlog/level"
+)
+
+// gatedWriter blocks every write until the gate is opened
+type gatedWriter struct {
+	mtx  sync.Mutex
+	buf  bytes.Buffer
+	gate chan struct{}
+}
+
+func (g *gatedWriter) Write(p []byte) (int, error) {
+	<-g.gate
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+	return g.buf.Write(p)
+}
+
+func (g *gatedWriter) String() string {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+	return g.buf.String()
+}
+
+func fillAsync(t *testing.T, p DropPolicy) (*AsyncWriter, *gatedWriter) {
+	t.Helper()
+
+	g := &gatedWriter{gate: make(chan struct{})}
+	w := NewAsyncWriter(AsyncParams{Writer: g, Capacity: 2, BatchSize: 1, Policy: p, MinLevel: ll.WARN})
+
+	// the first record gets picked up by the background goroutine and blocks in the gated write
+	w.WriteLevel(ll.INFO, []byte("a\n"))
+	deadline := time.Now().Add(time.Second)
+	for {
+		w.mtx.Lock()
+		busy := w.inFlight
+		w.mtx.Unlock()
+		if busy {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("background writer never picked up the first record")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	w.WriteLevel(ll.INFO, []byte("b\n"))
+	w.WriteLevel(ll.INFO, []byte("c\n"))
+	return w, g
+}
+
+func TestAsyncDropPolicies(t *testing.T) {
+	cases := []struct {
+		policy   DropPolicy
+		level    ll.LogLevel
+		expected string
+		dropped  uint64
+	}{
+		{DropNewest, ll.INFO, "a\nb\nc\n", 1},
+		{DropOldest, ll.INFO, "a\nc\nd\n", 1},
+		{DropBelowLevel, ll.INFO, "a\nb\nc\n", 1},
+	}
+
+	for _, c := range cases {
+		w, g := fillAsync(t, c.policy)
+		w.WriteLevel(c.level, []byte("d\n"))
+		close(g.gate)
+
+		if err := w.Flush(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if g.String() != c.expected {
+			t.Fatalf("policy %d: expected %q, got %q", c.policy, c.expected, g.String())
+		}
+		if w.Dropped() != c.dropped {
+			t.Fatalf("policy %d: expected %d dropped, got %d", c.policy, c.dropped, w.Dropped())
+		}
+		w.Close()
+	}
+}
+
+func TestAsyncFlushAndClose(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewAsyncWriter(AsyncParams{Writer: &buf, Capacity: 8, BatchSize: 3})
+
+	for i := 0; i < 100; i++ {
+		WriteString(w, "line\n")
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := bytes.Count(buf.Bytes(), []byte("\n")); got != 100 {
+		t.Fatalf("expected 100 lines after close, got %d", got)
+	}
+	if s := w.Stats(); s.Written != 100 || s.Dropped != 0 {
+		t.Fatalf("unexpected stats: %+v", s)
+	}
+	if _, err := w.Write([]byte("late\n")); err != ErrAsyncClosed {
+		t.Fatalf("expected ErrAsyncClosed, got %v", err)
+	}
+}
+
+func TestAsyncFlushReturnsWhenTheContextIsDone(t *testing.T) {
+	w, g := fillAsync(t, Block)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := w.Flush(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expected the deadline error while the destination is blocked, got %v", err)
+	}
+
+	close(g.gate)
+	if err := FlushAll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if g.String() != "a\nb\nc\n" {
+		t.Fatalf("expected every record after FlushAll, got %q", g.String())
+	}
+	w.Close()
+	if _, ok := openAsync.Load(w); ok {
+		t.Fatal("expected a closed writer to be left out of FlushAll")
+	}
+}
+
+type closingBuffer struct {
+	bytes.Buffer
+}
+
+func (c *closingBuffer) Close() error {
+	return nil
+}
+
+func TestCloseForgetsPointerLocks(t *testing.T) {
+	var w = &closingBuffer{}
+	LockFor(w)
+	key, _ := lockKey(w)
+	if _, ok := fileLocks.Load(key); !ok {
+		t.Fatal("expected a lock for the writer")
+	}
+	if err := Close(w); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := fileLocks.Load(key); ok {
+		t.Fatal("expected the lock to be dropped when the writer is closed")
+	}
+}
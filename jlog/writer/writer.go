@@ -1,43 +1,72 @@
 package writer
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"reflect"
 	"sync"
 	"syscall"
 )
 
+// any io.Writer can be used as a log output (a sink),
+// if it also implements Syncer and/or io.Closer, then Sync/Close below will call those.
+
+type Syncer interface {
+	Sync() error
+}
+
+type fder interface {
+	Fd() uintptr
+}
+
 type SafeWriter struct {
-	w *os.File
+	w io.Writer
 	m *sync.RWMutex
 }
 
 var fileLocks sync.Map
 
-func lockKey(v *os.File) string {
+func lockKey(v io.Writer) (string, bool) {
 	if v == nil {
-		return "fd:stdout"
+		return "fd:stdout", true
 	}
 
-	var st syscall.Stat_t
-	if err := syscall.Fstat(int(v.Fd()), &st); err == nil {
-		return fmt.Sprintf("inode:%d:%d", st.Dev, st.Ino)
+	if f, ok := v.(fder); ok {
+		var st syscall.Stat_t
+		if err := syscall.Fstat(int(f.Fd()), &st); err == nil {
+			return fmt.Sprintf("inode:%d:%d", st.Dev, st.Ino), true
+		}
+		return fmt.Sprintf("fd:%d", f.Fd()), true
 	}
 
-	return fmt.Sprintf("fd:%d", v.Fd())
+	// non-file writers share a lock if they are the same pointer
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Chan, reflect.Func, reflect.UnsafePointer:
+		return fmt.Sprintf("ptr:%T:%x", v, rv.Pointer()), true
+	}
+
+	return "", false
 }
 
-func lockFor(v *os.File) *sync.RWMutex {
-	key := lockKey(v)
+// LockFor returns the lock shared by every writer pointing at the same file (by inode) or the same object.
+func LockFor(v io.Writer) *sync.RWMutex {
+	key, ok := lockKey(v)
+	if !ok {
+		return &sync.RWMutex{}
+	}
 	actual, _ := fileLocks.LoadOrStore(key, &sync.RWMutex{})
 	return actual.(*sync.RWMutex)
 }
 
-func NewSafeWriter(v *os.File) *SafeWriter {
+func NewSafeWriter(v io.Writer) *SafeWriter {
 	if v == nil {
 		v = os.Stdout
 	}
-	return &SafeWriter{w: v, m: lockFor(v)}
+	return &SafeWriter{w: v, m: LockFor(v)}
 }
 
 func (sw *SafeWriter) Lock() {
@@ -51,7 +80,7 @@ func (sw *SafeWriter) Unlock() {
 func (sw *SafeWriter) WriteString(p string) (n int, err error) {
 	sw.Lock()
 	defer sw.Unlock()
-	return sw.w.WriteString(p)
+	return io.WriteString(sw.w, p)
 }
 
 func (sw *SafeWriter) Write(p []byte) (n int, err error) {
@@ -60,10 +89,136 @@ func (sw *SafeWriter) Write(p []byte) (n int, err error) {
 	return sw.w.Write(p)
 }
 
-func WriteString(w *os.File, p string) (n int, err error) {
+func (sw *SafeWriter) Sync() error {
+	sw.Lock()
+	defer sw.Unlock()
+	return doSync(sw.w)
+}
+
+func WriteString(w io.Writer, p string) (n int, err error) {
+	if aw, ok := w.(*AsyncWriter); ok {
+		// has its own lock, the destination gets locked by the background goroutine
+		return aw.Write([]byte(p))
+	}
 	return NewSafeWriter(w).WriteString(p)
 }
 
-func Write(w *os.File, p []byte) (n int, err error) {
+func Write(w io.Writer, p []byte) (n int, err error) {
+	if aw, ok := w.(*AsyncWriter); ok {
+		return aw.Write(p)
+	}
 	return NewSafeWriter(w).Write(p)
 }
+
+func doSync(w io.Writer) error {
+	if s, ok := w.(Syncer); ok {
+		if err := s.Sync(); err != nil && !IsUnsyncable(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Sync flushes the writer if it supports it, writers without Sync() are a no-op.
+func Sync(w io.Writer) error {
+	if w == nil {
+		return nil
+	}
+	return NewSafeWriter(w).Sync()
+}
+
+// Close closes the writer if it supports it, stdout/stderr are never closed.
+// An AsyncWriter is drained and then its destination is closed too.
+// Closing an already closed file is not an error, since outputs can be shared by loggers.
+func Close(w io.Writer) error {
+	if w == nil || IsStdio(w) {
+		return nil
+	}
+	if aw, ok := w.(*AsyncWriter); ok {
+		if err := aw.Close(); err != nil {
+			return err
+		}
+		return Close(aw.Destination())
+	}
+	if c, ok := w.(io.Closer); ok {
+		var m = LockFor(w)
+		m.Lock()
+		defer m.Unlock()
+		if err := c.Close(); err != nil && !errors.Is(err, os.ErrClosed) {
+			return err
+		}
+		forgetLock(w)
+	}
+	return nil
+}
+
+// forgetLock drops the lock of a closed writer which is keyed by its pointer, so that closed sinks
+// do not pile up in fileLocks. Files keep theirs, other handles to the same inode may still use it.
+func forgetLock(w io.Writer) {
+	if _, ok := w.(fder); ok {
+		return
+	}
+	if key, ok := lockKey(w); ok {
+		fileLocks.Delete(key)
+	}
+}
+
+// Flush drains an AsyncWriter and syncs the destination, for other writers it is the same as Sync.
+func Flush(ctx context.Context, w io.Writer) error {
+	if aw, ok := w.(*AsyncWriter); ok {
+		if err := aw.Flush(ctx); err != nil {
+			return err
+		}
+		w = aw.Destination()
+	}
+	if err := Sync(w); err != nil && !errors.Is(err, os.ErrClosed) {
+		return err
+	}
+	return nil
+}
+
+// IsStdio is true if the writer is the process' stdout or stderr.
+func IsStdio(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok || f == nil {
+		return false
+	}
+	return f.Fd() == os.Stdout.Fd() || f.Fd() == os.Stderr.Fd()
+}
+
+// IsStdout is true if the writer is the process' stdout.
+func IsStdout(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	return ok && f != nil && f.Fd() == os.Stdout.Fd()
+}
+
+// Describe names a writer for admin endpoints and dumps: stdout, stderr, the file name or the writer's type.
+func Describe(w io.Writer) string {
+	switch v := w.(type) {
+	case nil:
+		return "stdout"
+	case *AsyncWriter:
+		return "async:" + Describe(v.Destination())
+	case *SafeWriter:
+		return Describe(v.w)
+	case *os.File:
+		if v.Fd() == os.Stdout.Fd() {
+			return "stdout"
+		}
+		if v.Fd() == os.Stderr.Fd() {
+			return "stderr"
+		}
+		return v.Name()
+	case interface{ Name() string }:
+		return v.Name()
+	}
+	return fmt.Sprintf("%T", w)
+}
+
+// IsUnsyncable is true for fsync errors from ttys and pipes, which can't be synced.
+func IsUnsyncable(err error) bool {
+	if pe, ok := err.(*os.PathError); ok {
+		err = pe.Err
+	}
+	return err == syscall.EINVAL || err == syscall.ENOTSUP
+}
@@ -0,0 +1,24 @@
+package callsite
+
+// Frames in the jlog packages are skipped when looking for the caller of a logging call (see hlpr.IsLibraryFrame),
+// so the tests of json-logging log from here to get a call site they can check.
+
+// Call calls f, the line of the call is the call site.
+func Call(f func()) {
+	f()
+}
+
+// CallAgain is Call from another line, for tests which need a second call site.
+func CallAgain(f func()) {
+	f()
+}
+
+// Helper calls f through Call, like a logging helper would, for tests of Opts.SkipFrames.
+func Helper(f func()) {
+	Call(f)
+}
+
+// Panic panics from here, so that the stack trace starts outside of the jlog packages.
+func Panic(v interface{}) {
+	panic(v)
+}